@@ -1,11 +1,10 @@
 package mytgbot
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"io"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -18,10 +17,22 @@ type (
 )
 
 const (
-	ErrStatusNil          ErrStatus = 0
-	ErrStatusKicked       ErrStatus = 1   //对话已被踢出群聊
-	ErrStatusGroupDeleted ErrStatus = 2   //群组被删除
-	ErrStatusUnknown      ErrStatus = 100 //未知的错误
+	ErrStatusNil                     ErrStatus = 0
+	ErrStatusKicked                  ErrStatus = 1   //对话已被踢出群聊
+	ErrStatusGroupDeleted            ErrStatus = 2   //群组被删除
+	ErrStatusBlocked                 ErrStatus = 3   //用户屏蔽了机器人或未开启对话
+	ErrStatusChatNotFound            ErrStatus = 4   //对话不存在
+	ErrStatusUserDeactivated         ErrStatus = 5   //用户已注销
+	ErrStatusNotEnoughRights         ErrStatus = 6   //机器人权限不足
+	ErrStatusMessageToEditNotFound   ErrStatus = 7   //要编辑的消息不存在
+	ErrStatusMessageCantBeDeleted    ErrStatus = 8   //消息无法删除
+	ErrStatusMessageToDeleteNotFound ErrStatus = 9   //要删除的消息不存在
+	ErrStatusNotModified             ErrStatus = 10  //消息内容未修改
+	ErrStatusTooManyRequests         ErrStatus = 11  //触发限流，需等待 retry_after
+	ErrStatusMigrated                ErrStatus = 12  //群组已升级为超级群组，需迁移 chat id
+	ErrStatusServerError             ErrStatus = 13  //Telegram 服务端错误(5xx)
+	ErrStatusNetwork                 ErrStatus = 14  //网络错误或超时
+	ErrStatusUnknown                 ErrStatus = 100 //未知的错误
 )
 
 type (
@@ -75,18 +86,30 @@ func CheckErrStatus(err error) ErrStatus {
 		return ErrStatusNil
 	}
 
-	if strings.Contains(err.Error(), "Forbidden: bot was kicked") {
-		// 对话已被踢出群聊 修改群配置
-		return ErrStatusKicked
-	} else if strings.Contains(err.Error(), "Forbidden: the group chat was deleted") {
-		//群组被删除 修改群配置
-		return ErrStatusGroupDeleted
+	if apiErr, ok := AsAPIError(err); ok {
+		switch {
+		case apiErr.ErrorCode == http.StatusTooManyRequests || apiErr.Parameters.RetryAfter > 0:
+			return ErrStatusTooManyRequests
+		case apiErr.Parameters.MigrateToChatID != 0:
+			return ErrStatusMigrated
+		case apiErr.ErrorCode >= http.StatusInternalServerError:
+			return ErrStatusServerError
+		}
+	} else if isNetworkErr(err) {
+		return ErrStatusNetwork
+	}
+
+	desc := strings.ToLower(errDescription(err))
+	for _, rule := range errStatusRules {
+		if strings.Contains(desc, rule.text) {
+			return rule.status
+		}
 	}
 
 	return ErrStatusUnknown
 }
 
-func sendMessage(bot *tgbotapi.BotAPI, chattable tgbotapi.Chattable) (*tgbotapi.Message, error) {
+func sendMessage(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, chattable tgbotapi.Chattable) (*tgbotapi.Message, error) {
 	if bot == nil {
 		return nil, fmt.Errorf("bot api is nil")
 	}
@@ -95,7 +118,7 @@ func sendMessage(bot *tgbotapi.BotAPI, chattable tgbotapi.Chattable) (*tgbotapi.
 		return nil, fmt.Errorf("chattable is nil")
 	}
 
-	sendMsg, err := bot.Send(chattable)
+	sendMsg, err := botSend(ctx, bot, chatId, chattable)
 	if err != nil {
 		return nil, err
 	}
@@ -103,30 +126,87 @@ func sendMessage(bot *tgbotapi.BotAPI, chattable tgbotapi.Chattable) (*tgbotapi.
 	return &sendMsg, nil
 }
 
+// 按当前限流与重试策略调用 bot.Send
+func botSend(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
+	var ret tgbotapi.Message
+	//无法识别的类型按需要限流处理，宁可多等也不要漏掉
+	method := chattableMethod(chattable)
+	limited := method == "" || isRateLimitedMethod(method)
+	err := withRetry(ctx, nil, func() (err error) {
+		if limited {
+			if err = waitRateLimit(ctx, nil, chatId); err != nil {
+				return err
+			}
+		}
+		ret, err = botWithContext(ctx, bot).Send(chattable)
+		return err
+	})
+	return ret, err
+}
+
+// 返回一个请求都绑定 ctx 的 BotAPI 副本，ctx 取消时正在进行的 HTTP 请求随之取消
+func botWithContext(ctx context.Context, bot *tgbotapi.BotAPI) *tgbotapi.BotAPI {
+	if ctx == nil || ctx == context.Background() || bot == nil || bot.Client == nil {
+		return bot
+	}
+
+	b := *bot
+	b.Client = ctxHTTPClient{ctx: ctx, client: bot.Client}
+	return &b
+}
+
+type ctxHTTPClient struct {
+	ctx    context.Context
+	client tgbotapi.HTTPClient
+}
+
+func (c ctxHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req.WithContext(c.ctx))
+}
+
+// 以 io.Reader 上传的文件无法重放，不做重试
+func uploadRetryCtx(ctx context.Context, fileData tgbotapi.RequestFileData) context.Context {
+	switch fileData.(type) {
+	case tgbotapi.FileReader, *tgbotapi.FileReader:
+		return ContextWithRetryPolicy(ctx, NoRetry)
+	}
+	return ctx
+}
+
 func SendMessage(bot *tgbotapi.BotAPI, chatId int64, message string, configCb func(messageCfg *tgbotapi.MessageConfig)) (*tgbotapi.Message, error) {
+	return SendMessageContext(context.Background(), bot, chatId, message, configCb)
+}
+
+func SendMessageContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, message string, configCb func(messageCfg *tgbotapi.MessageConfig)) (*tgbotapi.Message, error) {
 	sendMsg := tgbotapi.NewMessage(chatId, message)
 	if configCb != nil {
 		configCb(&sendMsg)
 	}
 
-	return sendMessage(bot, sendMsg)
+	return sendMessage(ctx, bot, chatId, sendMsg)
 }
 
+// SendMessageByAutoDel 发送消息并在 autoDele 之后删除，SendPhotoByAutoDel 等同理。
+// 默认使用内存中的删除调度器，进程重启后尚未执行的删除会丢失；需要重启后继续删除时，
+// 启动时调用 SetDeleteScheduler(bot, s)，其中 s 由 NewDeleteScheduler(BotDeleteFunc(bot), NewFileDeleteStore(path)) 创建
 func SendMessageByAutoDel(bot *tgbotapi.BotAPI, chatId int64, message string, configCb func(messageCfg *tgbotapi.MessageConfig), autoDele time.Duration) error {
-	msg, err := SendMessage(bot, chatId, message, configCb)
+	return SendMessageByAutoDelContext(context.Background(), bot, chatId, message, configCb, autoDele)
+}
+
+func SendMessageByAutoDelContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, message string, configCb func(messageCfg *tgbotapi.MessageConfig), autoDele time.Duration) error {
+	msg, err := SendMessageContext(ctx, bot, chatId, message, configCb)
 	if err != nil {
 		return err
 	}
 
-	go func(bt *tgbotapi.BotAPI, msgID int, groupID int64) {
-		time.Sleep(autoDele)
-		_, _ = bt.Request(tgbotapi.NewDeleteMessage(groupID, msgID))
-	}(bot, msg.MessageID, chatId)
-
-	return nil
+	return deleteSchedulerOf(bot).Schedule(chatId, msg.MessageID, autoDele)
 }
 
 func SendPhoto(bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.PhotoConfig)) (messageID int, imageFileId string, err error) {
+	return SendPhotoContext(context.Background(), bot, chatId, imageFileFn, configCb)
+}
+
+func SendPhotoContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.PhotoConfig)) (messageID int, imageFileId string, err error) {
 	var fileData tgbotapi.RequestFileData = nil
 	if imageFileFn != nil {
 		fileData = imageFileFn()
@@ -142,7 +222,7 @@ func SendPhoto(bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.R
 	}
 
 	// 发送图片消息
-	uploadResp, err := bot.Send(photo)
+	uploadResp, err := botSend(uploadRetryCtx(ctx, fileData), bot, chatId, photo)
 	if err != nil {
 		return 0, "", err
 	}
@@ -151,6 +231,10 @@ func SendPhoto(bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.R
 }
 
 func SendAnimation(bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.AnimationConfig)) (messageID int, animationFileId string, err error) {
+	return SendAnimationContext(context.Background(), bot, chatID, animationFileFn, configCb)
+}
+
+func SendAnimationContext(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.AnimationConfig)) (messageID int, animationFileId string, err error) {
 	var fileData tgbotapi.RequestFileData = nil
 	if animationFileFn != nil {
 		fileData = animationFileFn()
@@ -166,7 +250,7 @@ func SendAnimation(bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tg
 	}
 
 	// 发送图片消息
-	uploadResp, err := bot.Send(animationMsg)
+	uploadResp, err := botSend(uploadRetryCtx(ctx, fileData), bot, chatID, animationMsg)
 	if err != nil {
 		return 0, "", err
 	}
@@ -174,54 +258,48 @@ func SendAnimation(bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tg
 	return uploadResp.MessageID, uploadResp.Animation.FileID, nil
 }
 
-func GetChatDesc(bot *tgbotapi.BotAPI, chatID int64) (tgbotapi.Chat, error) {
-	return bot.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{
-		ChatID: chatID,
-	}})
+func SendPhotoByAutoDel(bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.PhotoConfig), autoDele time.Duration) (messageID int, imageFileId string, err error) {
+	return SendPhotoByAutoDelContext(context.Background(), bot, chatId, imageFileFn, configCb, autoDele)
 }
 
-func GetChatByToken(token string, groupID int64) (*TelegramChat, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getChat?chat_id=%d", token, groupID)
-	resp, err := http.Get(apiURL)
+func SendPhotoByAutoDelContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, imageFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.PhotoConfig), autoDele time.Duration) (messageID int, imageFileId string, err error) {
+	messageID, imageFileId, err = SendPhotoContext(ctx, bot, chatId, imageFileFn, configCb)
 	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	var result ChatResp[TelegramChat]
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return 0, "", err
 	}
 
-	if !result.Ok {
-		return nil, fmt.Errorf("failed to get chat info")
-	}
+	return messageID, imageFileId, deleteSchedulerOf(bot).Schedule(chatId, messageID, autoDele)
+}
 
-	return &result.Result, nil
+func SendAnimationByAutoDel(bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.AnimationConfig), autoDele time.Duration) (messageID int, animationFileId string, err error) {
+	return SendAnimationByAutoDelContext(context.Background(), bot, chatID, animationFileFn, configCb, autoDele)
 }
 
-func GetUserByToken(token string, userID int64) (*TelegramUser, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getChat?chat_id=%d", token, userID)
-	resp, err := http.Get(apiURL)
+func SendAnimationByAutoDelContext(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, animationFileFn func() tgbotapi.RequestFileData, configCb func(photoConfig *tgbotapi.AnimationConfig), autoDele time.Duration) (messageID int, animationFileId string, err error) {
+	messageID, animationFileId, err = SendAnimationContext(ctx, bot, chatID, animationFileFn, configCb)
 	if err != nil {
-		return nil, err
+		return 0, "", err
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
 
-	var result ChatResp[TelegramUser]
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
+	return messageID, animationFileId, deleteSchedulerOf(bot).Schedule(chatID, messageID, autoDele)
+}
 
-	if !result.Ok {
-		return nil, fmt.Errorf("failed to get chat info")
-	}
+func GetChatDesc(bot *tgbotapi.BotAPI, chatID int64) (tgbotapi.Chat, error) {
+	return GetChatDescContext(context.Background(), bot, chatID)
+}
+
+func GetChatDescContext(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) (tgbotapi.Chat, error) {
+	return botWithContext(ctx, bot).GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{
+		ChatID: chatID,
+	}})
+}
 
-	return &result.Result, nil
+func GetChatByToken(token string, groupID int64) (*TelegramChat, error) {
+	return clientByToken(token).GetChat(context.Background(), groupID)
+}
+
+func GetUserByToken(token string, userID int64) (*TelegramUser, error) {
+	return clientByToken(token).GetUser(context.Background(), userID)
 }
 
 func GenUserNameLink(userName string) string {
@@ -229,112 +307,60 @@ func GenUserNameLink(userName string) string {
 }
 
 func SendMessageByToken(token string, toChatId int64, message string, configFn func(values url.Values)) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", token)
-	// 构造请求参数
-	data := url.Values{}
-	data.Set("chat_id", fmt.Sprintf("%d", toChatId))
-	data.Set("text", message)
-	if configFn != nil {
-		configFn(data) //此处可以在外转增加一些参数 ：parse_mode / reply_markup
-	}
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("消息发送失败，状态码: %d", resp.StatusCode)
-	}
-
-	return nil
+	//configFn 可以在外转增加一些参数 ：parse_mode / reply_markup
+	_, err := clientByToken(token).SendMessage(context.Background(), toChatId, message, configFn)
+	return err
 }
 
 func SendPhotoByToken(token string, toChatId int64, photoName string, photoData []byte, caption string, configFn func(values *multipart.Writer)) error {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendPhoto", token)
-	// 构造表单数据
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	// 添加 chat_id 字段
-	if err := writer.WriteField("chat_id", fmt.Sprintf("%d", toChatId)); err != nil {
-		return fmt.Errorf("failed to add chat_id: %w", err)
-	}
-
-	// 添加 caption 字段（可选）
-	if caption != "" {
-		if err := writer.WriteField("caption", caption); err != nil {
-			return fmt.Errorf("failed to add caption: %w", err)
-		}
-	}
-
-	if configFn != nil {
-		configFn(writer) //此处可以在外转增加一些参数 ：parse_mode / reply_markup
-	}
-
-	// 添加图片数据
-	part, err := writer.CreateFormFile("photo", photoName)
-	if err != nil {
-		return fmt.Errorf("failed to create form file: %w", err)
-	}
-
-	// 写入图片字节数据
-	if _, err := io.Copy(part, bytes.NewReader(photoData)); err != nil {
-		return fmt.Errorf("failed to copy photo data: %w", err)
-	}
-
-	// 关闭表单
-	if err := writer.Close(); err != nil {
-		return fmt.Errorf("failed to close writer: %w", err)
-	}
-
-	// 创建 HTTP 请求
-	req, err := http.NewRequest("POST", apiURL, body)
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
+	//configFn 可以在外转增加一些参数 ：parse_mode / reply_markup
+	_, err := clientByToken(token).SendPhoto(context.Background(), toChatId, photoName, photoData, caption, configFn)
+	return err
+}
 
-	// 执行请求
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
+func SetWebhookByToken(token string, cfg WebhookConfig) error {
+	return clientByToken(token).SetWebhook(context.Background(), cfg)
+}
 
-	// 检查响应
-	if resp.StatusCode != http.StatusOK {
-		respBody, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("Telegram API returned error: %s", string(respBody))
-	}
+func DeleteWebhookByToken(token string, dropPendingUpdates bool) error {
+	return clientByToken(token).DeleteWebhook(context.Background(), dropPendingUpdates)
+}
 
-	return nil
+func GetWebhookInfoByToken(token string) (*WebhookInfo, error) {
+	return clientByToken(token).GetWebhookInfo(context.Background())
 }
 
 func EditMessageCaption(bot *tgbotapi.BotAPI, chatId int64, editMessageID int, caption string, configFn func(editMsgConfig *tgbotapi.EditMessageCaptionConfig)) (tgbotapi.Message, error) {
+	return EditMessageCaptionContext(context.Background(), bot, chatId, editMessageID, caption, configFn)
+}
+
+func EditMessageCaptionContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, editMessageID int, caption string, configFn func(editMsgConfig *tgbotapi.EditMessageCaptionConfig)) (tgbotapi.Message, error) {
 	editMsg := tgbotapi.NewEditMessageCaption(chatId, editMessageID, caption)
 	if configFn != nil {
 		configFn(&editMsg)
 	}
 
-	return bot.Send(editMsg)
+	return botSend(ctx, bot, chatId, editMsg)
 }
 
 func EditMessage(bot *tgbotapi.BotAPI, chatId int64, editMessageID int, text string, configFn func(editMsgConfig *tgbotapi.EditMessageTextConfig)) (tgbotapi.Message, error) {
+	return EditMessageContext(context.Background(), bot, chatId, editMessageID, text, configFn)
+}
+
+func EditMessageContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, editMessageID int, text string, configFn func(editMsgConfig *tgbotapi.EditMessageTextConfig)) (tgbotapi.Message, error) {
 	editMsg := tgbotapi.NewEditMessageText(chatId, editMessageID, text)
 	if configFn != nil {
 		configFn(&editMsg)
 	}
 
-	return bot.Send(editMsg)
+	return botSend(ctx, bot, chatId, editMsg)
 }
 
 func EditMessageSafe(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, text string, configFn func(editMsgConfig *tgbotapi.EditMessageTextConfig)) (*tgbotapi.Message, error) {
+	return EditMessageSafeContext(context.Background(), bot, msg, text, configFn)
+}
+
+func EditMessageSafeContext(ctx context.Context, bot *tgbotapi.BotAPI, msg *tgbotapi.Message, text string, configFn func(editMsgConfig *tgbotapi.EditMessageTextConfig)) (*tgbotapi.Message, error) {
 	if bot == nil || msg == nil {
 		return nil, fmt.Errorf("bot or msg is nil ")
 	}
@@ -344,7 +370,7 @@ func EditMessageSafe(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, text string, c
 
 	// 判断类型：纯文本消息可直接编辑
 	if msg.Text != "" && msg.Photo == nil {
-		if ret, err := EditMessage(bot, chatID, messageID, text, configFn); err == nil {
+		if ret, err := EditMessageContext(ctx, bot, chatID, messageID, text, configFn); err == nil {
 			return &ret, nil
 		} else {
 			if IsErrNotModified(err) { //说明是没有编辑的问题，忽略这个错误 ,不用重新发消息
@@ -355,8 +381,8 @@ func EditMessageSafe(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, text string, c
 	}
 
 	// 类型不匹配：删旧发新
-	_, _ = DelMessage(bot, chatID, messageID)
-	return SendMessage(bot, chatID, text, func(messageCfg *tgbotapi.MessageConfig) {
+	_, _ = DelMessageContext(ctx, bot, chatID, messageID)
+	return SendMessageContext(ctx, bot, chatID, text, func(messageCfg *tgbotapi.MessageConfig) {
 		if configFn != nil {
 			m := tgbotapi.EditMessageTextConfig{} // 仅用于获取markup配置
 			configFn(&m)
@@ -370,6 +396,14 @@ func EditMessageSafe(bot *tgbotapi.BotAPI, msg *tgbotapi.Message, text string, c
 }
 
 func EditMessagePhotoSafe(bot *tgbotapi.BotAPI,
+	msg *tgbotapi.Message,
+	caption string,
+	getPhotoFn func() tgbotapi.RequestFileData, // 动态提供图片
+	configFn func(editMsgConfig *tgbotapi.EditMessageCaptionConfig)) (*tgbotapi.Message, error) {
+	return EditMessagePhotoSafeContext(context.Background(), bot, msg, caption, getPhotoFn, configFn)
+}
+
+func EditMessagePhotoSafeContext(ctx context.Context, bot *tgbotapi.BotAPI,
 	msg *tgbotapi.Message,
 	caption string,
 	getPhotoFn func() tgbotapi.RequestFileData, // 动态提供图片
@@ -394,7 +428,7 @@ func EditMessagePhotoSafe(bot *tgbotapi.BotAPI,
 				configFn(&edit)
 			}
 
-			if ret, err := bot.Send(edit); err == nil {
+			if ret, err := botSend(ctx, bot, chatID, edit); err == nil {
 				return &ret, nil
 			}
 		}
@@ -402,7 +436,7 @@ func EditMessagePhotoSafe(bot *tgbotapi.BotAPI,
 	}
 
 	// 类型不匹配：删旧发新
-	_, _ = DelMessage(bot, chatID, messageID)
+	_, _ = DelMessageContext(ctx, bot, chatID, messageID)
 	newMsg := tgbotapi.NewPhoto(chatID, photoData)
 	newMsg.Caption = caption
 	if configFn != nil {
@@ -415,7 +449,7 @@ func EditMessagePhotoSafe(bot *tgbotapi.BotAPI,
 		}
 	}
 
-	ret, err := bot.Send(newMsg)
+	ret, err := botSend(uploadRetryCtx(ctx, photoData), bot, chatID, newMsg)
 	if err != nil {
 		return nil, err
 	}
@@ -423,138 +457,53 @@ func EditMessagePhotoSafe(bot *tgbotapi.BotAPI,
 }
 
 func AlertCallback(bot *tgbotapi.BotAPI, cbId string, message string, configFn func(callbackConfig *tgbotapi.CallbackConfig)) (tgbotapi.Message, error) {
+	return AlertCallbackContext(context.Background(), bot, cbId, message, configFn)
+}
+
+func AlertCallbackContext(ctx context.Context, bot *tgbotapi.BotAPI, cbId string, message string, configFn func(callbackConfig *tgbotapi.CallbackConfig)) (tgbotapi.Message, error) {
 	alert := tgbotapi.NewCallbackWithAlert(cbId, message)
 	if configFn != nil {
 		configFn(&alert)
 	}
 
-	return bot.Send(alert)
+	return botWithContext(ctx, bot).Send(alert)
 }
 
 func PingMessage(bot *tgbotapi.BotAPI, chatID int64, messagaId int, notifaicationFlag bool) error {
+	return PingMessageContext(context.Background(), bot, chatID, messagaId, notifaicationFlag)
+}
+
+func PingMessageContext(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, messagaId int, notifaicationFlag bool) error {
 	pinMsg := tgbotapi.PinChatMessageConfig{
 		ChatID:              chatID,
 		MessageID:           messagaId,
 		DisableNotification: notifaicationFlag,
 	}
 
-	_, err := bot.Request(pinMsg)
+	_, err := botWithContext(ctx, bot).Request(pinMsg)
 	return err
 }
 
 func GetBotInfo(token string) (*BotInfo, error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/getMe", token)
-
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	var result BotInfo
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
-	}
-
-	if !result.Ok {
-		return nil, fmt.Errorf("failed to get bot info")
-	}
-
-	return &result, nil
+	return clientByToken(token).GetMe(context.Background())
 }
 
 // 生成群永久链接，这个链接中永久的，唯一的，多次生成，则新的替换成旧的 。
 func CreatePermanentInviteLink(token string, chatId int64) (ret string, err error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/exportChatInviteLink", token)
-	// 构造请求参数
-	data := url.Values{}
-	data.Set("chat_id", fmt.Sprintf("%d", chatId))
-
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("消息发送失败，状态码: %d", resp.StatusCode)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// 解析响应
-	var result struct {
-		Ok     bool   `json:"ok"`
-		Result string `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	// 返回邀请链接
-	if !result.Ok {
-		return "", fmt.Errorf("failed to get invite link")
-	}
-
-	return result.Result, nil
-
+	return clientByToken(token).ExportChatInviteLink(context.Background(), chatId)
 }
 
 // 生成临时邀请链接 ，可为不同人生成，场景更丰富
 func CreateTempInviteLink(token string, chatId int64, name string, expireDate time.Time, maxLimit int, joinCheck bool) (ret *InviteTempData, err error) {
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/createChatInviteLink", token)
-	// 构造请求参数
-	data := url.Values{}
-	data.Set("chat_id", fmt.Sprintf("%d", chatId))
-	data.Set("name", name)
-	data.Set("expire_date", fmt.Sprintf("%d", expireDate.Unix()))
-	data.Set("member_limit", fmt.Sprintf("%d", maxLimit))
-	data.Set("creates_join_request", fmt.Sprintf("%t", joinCheck))
-
-	resp, err := http.PostForm(apiURL, data)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// 检查响应状态
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("消息发送失败，状态码: %d", resp.StatusCode)
-	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-
-	// 解析响应
-	var result struct {
-		Ok     bool           `json:"ok"`
-		Result InviteTempData `json:"result"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %v", err)
-	}
-
-	// 返回邀请链接
-	if !result.Ok {
-		return nil, fmt.Errorf("failed to get invite link")
-	}
-
-	return &result.Result, nil
-
+	return clientByToken(token).CreateChatInviteLink(context.Background(), chatId, name, expireDate, maxLimit, joinCheck)
 }
 
 func DelMessage(bot *tgbotapi.BotAPI, chatId int64, messageID int) (*tgbotapi.APIResponse, error) {
-	return bot.Request(tgbotapi.NewDeleteMessage(chatId, messageID))
+	return DelMessageContext(context.Background(), bot, chatId, messageID)
+}
+
+func DelMessageContext(ctx context.Context, bot *tgbotapi.BotAPI, chatId int64, messageID int) (*tgbotapi.APIResponse, error) {
+	return botWithContext(ctx, bot).Request(tgbotapi.NewDeleteMessage(chatId, messageID))
 }
 
 // 关于markdown 格式的特别显示
@@ -588,5 +537,5 @@ func WebhookHandler(w http.ResponseWriter, r *http.Request, cbFun func(update tg
 }
 
 func IsErrNotModified(err error) bool {
-	return err != nil && strings.Contains(err.Error(), "message is not modified")
+	return err != nil && strings.Contains(errDescription(err), "message is not modified")
 }
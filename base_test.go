@@ -1,7 +1,9 @@
 package mytgbot
 
 import (
+	"context"
 	"encoding/json"
+	"net/http"
 	"testing"
 	"time"
 )
@@ -34,3 +36,34 @@ func TestGetChatByToken(t *testing.T) {
 	jb, _ := json.Marshal(chat)
 	t.Log("get chat is :", string(jb))
 }
+
+func TestSendMessageContextCancel(t *testing.T) {
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if method == "getMe" {
+			return `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		return `{"ok":true,"result":{"message_id":1,"chat":{"id":100}}}`
+	})
+
+	bot, err := NewClient("token", WithBaseURL(srv.URL)).NewBotAPI()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := SendMessageContext(ctx, bot, 100, "hello", nil); err == nil {
+		t.Error("expect context error")
+	}
+
+	if cost := time.Since(start); cost > time.Millisecond*500 {
+		t.Error("request was not cancelled:", cost)
+	}
+}
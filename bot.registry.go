@@ -0,0 +1,230 @@
+package mytgbot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type (
+	// BotEntry 注册表中的一个机器人，更新经 WebhookServer 交给 Dispatcher
+	BotEntry struct {
+		ID          int64
+		Username    string
+		Bot         *tgbotapi.BotAPI
+		Client      *Client
+		Dispatcher  *Dispatcher
+		SecretToken string //setWebhook 时必须带上，见 BotRegistry.Add
+		server      *WebhookServer
+	}
+
+	// BotRegistry 在一个进程中托管多个机器人：每个机器人的 webhook 路径为 prefix + bot id，
+	// 通过 Mount 挂到同一个 http.ServeMux 上，运行中可随时 Add/Remove；
+	// bot id 是公开的，因此每个机器人都有各自的 secret token
+	BotRegistry struct {
+		prefix string
+		secret []byte
+		opts   []WebhookOption
+
+		mu    sync.RWMutex
+		bots  map[int64]*BotEntry
+		names map[string]int64 //小写用户名 -> id
+	}
+)
+
+var (
+	ErrBotNotFound       = errors.New("bot not found")
+	ErrBotSecretRequired = errors.New("bot webhook secret token required")
+)
+
+// NewBotRegistry prefix 如 "/hook/"；secret 为主密钥，每个机器人的 secret token 由它与 bot id 计算得出，
+// 重启后保持不变，需持久保存；opts 为所有机器人共用的 webhook 选项
+func NewBotRegistry(prefix string, secret []byte, opts ...WebhookOption) *BotRegistry {
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	return &BotRegistry{
+		prefix: prefix,
+		secret: secret,
+		opts:   opts,
+		bots:   make(map[int64]*BotEntry),
+		names:  make(map[string]int64),
+	}
+}
+
+// Mount 把注册表挂到 mux，之后增删机器人不需要重新挂载
+func (r *BotRegistry) Mount(mux *http.ServeMux) {
+	mux.Handle(r.prefix, r)
+}
+
+// WebhookPath 机器人的 webhook 路径，setWebhook 时拼上域名即可
+func (r *BotRegistry) WebhookPath(botID int64) string {
+	return r.prefix + strconv.FormatInt(botID, 10)
+}
+
+// Add 添加机器人，setup 用于注册路由；opts 追加在共用选项之后。
+// secret token 默认由主密钥与 bot id 计算，也可在 opts 中以 WithSecretToken 指定（共用选项中的不生效），
+// 两者都没有时返回 ErrBotSecretRequired；需以 entry.SecretToken 调用 setWebhook。
+// 已存在同一 id 的机器人时替换，旧的 WebhookServer 在后台处理完剩余更新
+func (r *BotRegistry) Add(client *Client, setup func(d *Dispatcher), opts ...WebhookOption) (*BotEntry, error) {
+	bot, err := client.NewBotAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	d := NewDispatcher(bot)
+	if setup != nil {
+		setup(d)
+	}
+
+	all := append(append(append([]WebhookOption(nil), r.opts...), WithSecretToken(r.secretToken(bot.Self.ID))), opts...)
+	server := NewWebhookServer(d.ServeUpdate, all...)
+	if server.secret == "" {
+		return nil, fmt.Errorf("%w: bot %d", ErrBotSecretRequired, bot.Self.ID)
+	}
+	entry := &BotEntry{
+		ID:          bot.Self.ID,
+		Username:    bot.Self.UserName,
+		Bot:         bot,
+		Client:      client,
+		Dispatcher:  d,
+		SecretToken: server.secret,
+		server:      server,
+	}
+
+	r.mu.Lock()
+	old := r.bots[entry.ID]
+	if old != nil {
+		delete(r.names, strings.ToLower(old.Username))
+	}
+	r.bots[entry.ID] = entry
+	if entry.Username != "" {
+		r.names[strings.ToLower(entry.Username)] = entry.ID
+	}
+	r.mu.Unlock()
+
+	if old != nil {
+		go func() {
+			_ = old.close(context.Background())
+		}()
+	}
+	return entry, nil
+}
+
+func (r *BotRegistry) AddByToken(token string, setup func(d *Dispatcher), opts ...WebhookOption) (*BotEntry, error) {
+	return r.Add(clientByToken(token), setup, opts...)
+}
+
+// Remove 移除机器人，新的请求返回 404，等待已接收的更新处理完毕或 ctx 结束，
+// 之后关闭该机器人的删除调度器
+func (r *BotRegistry) Remove(ctx context.Context, botID int64) error {
+	r.mu.Lock()
+	entry := r.bots[botID]
+	if entry != nil {
+		delete(r.bots, botID)
+		delete(r.names, strings.ToLower(entry.Username))
+	}
+	r.mu.Unlock()
+
+	if entry == nil {
+		return fmt.Errorf("%w: %d", ErrBotNotFound, botID)
+	}
+	return entry.close(ctx)
+}
+
+func (r *BotRegistry) Get(botID int64) *BotEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.bots[botID]
+}
+
+// GetByUsername 用户名不区分大小写，可带 @
+func (r *BotRegistry) GetByUsername(username string) *BotEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.names[strings.ToLower(strings.TrimPrefix(username, "@"))]
+	if !ok {
+		return nil
+	}
+	return r.bots[id]
+}
+
+func (r *BotRegistry) List() []*BotEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ret := make([]*BotEntry, 0, len(r.bots))
+	for _, entry := range r.bots {
+		ret = append(ret, entry)
+	}
+	return ret
+}
+
+// Shutdown 停止所有机器人的 WebhookServer 与删除调度器
+func (r *BotRegistry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	entries := make([]*BotEntry, 0, len(r.bots))
+	for _, entry := range r.bots {
+		entries = append(entries, entry)
+	}
+	r.bots = make(map[int64]*BotEntry)
+	r.names = make(map[string]int64)
+	r.mu.Unlock()
+
+	var errs []error
+	for _, entry := range entries {
+		if err := entry.close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ServeHTTP 按路径中的 bot id 转给对应的 WebhookServer
+func (r *BotRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(req.URL.Path, r.prefix), "/"), 10, 64)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	entry := r.Get(id)
+	if entry == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	entry.server.ServeHTTP(w, req)
+}
+
+// 停止 WebhookServer，再关闭 SendMessageByAutoDel 等为该机器人创建的删除调度器，
+// 否则调度器的 goroutine 与 *tgbotapi.BotAPI 会一直留在全局表中
+func (e *BotEntry) close(ctx context.Context) error {
+	err := e.server.Shutdown(ctx)
+	if s, ok := deleteSchedulers.Load(e.Bot); ok {
+		_ = s.(*DeleteScheduler).Close()
+	}
+	SetDeleteScheduler(e.Bot, nil)
+	return err
+}
+
+// HMAC(主密钥, bot id)，同一机器人每次计算的结果相同，替换或重启后 Telegram 保存的 secret 仍然有效
+func (r *BotRegistry) secretToken(botID int64) string {
+	if len(r.secret) == 0 {
+		return ""
+	}
+
+	h := hmac.New(sha256.New, r.secret)
+	h.Write([]byte(strconv.FormatInt(botID, 10)))
+	return hex.EncodeToString(h.Sum(nil))
+}
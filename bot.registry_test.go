@@ -0,0 +1,105 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBotRegistry(t *testing.T) {
+	newClient := func(id int64, name string) *Client {
+		srv := newStubServer(t, func(method string, r *http.Request) string {
+			return fmt.Sprintf(`{"ok":true,"result":{"id":%d,"is_bot":true,"username":"%s"}}`, id, name)
+		})
+		return NewClient("token", WithBaseURL(srv.URL))
+	}
+
+	reg := NewBotRegistry("/hook", []byte("master"), WithSecretToken("shared"))
+	mux := http.NewServeMux()
+	reg.Mount(mux)
+
+	var hits [2]atomic.Int32
+	var entries [2]*BotEntry
+	for i, name := range []string{"first_bot", "second_bot"} {
+		entry, err := reg.Add(newClient(int64(i+1), name), func(d *Dispatcher) {
+			d.Handle(func(c *UpdateContext) error {
+				hits[i].Add(1)
+				return nil
+			})
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		entries[i] = entry
+	}
+
+	if entries[0].SecretToken == "" || entries[0].SecretToken == entries[1].SecretToken || entries[0].SecretToken == "shared" {
+		t.Error("expect distinct derived secrets, got:", entries[0].SecretToken, entries[1].SecretToken)
+	}
+
+	//重启后同一机器人的 secret 不变
+	again, err := NewBotRegistry("/hook", []byte("master")).Add(newClient(1, "first_bot"), nil)
+	if err != nil || again.SecretToken != entries[0].SecretToken {
+		t.Error("expect stable secret, got:", again, err)
+	}
+
+	if _, err := NewBotRegistry("/hook", nil).Add(newClient(1, "first_bot"), nil); !errors.Is(err, ErrBotSecretRequired) {
+		t.Error("expect ErrBotSecretRequired, got:", err)
+	}
+	if entry, err := NewBotRegistry("/hook", nil).Add(newClient(1, "first_bot"), nil, WithSecretToken("own")); err != nil || entry.SecretToken != "own" {
+		t.Error("expect per-bot secret, got:", entry, err)
+	}
+
+	post := func(path, secret string) int {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"update_id":1,"message":{"text":"hi","chat":{"id":1}}}`))
+		r.Header.Set(secretTokenHeader, secret)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post(reg.WebhookPath(2), entries[0].SecretToken); code != http.StatusUnauthorized {
+		t.Error("expect other bot's secret to be rejected, got:", code)
+	}
+	if code := post(reg.WebhookPath(2), entries[1].SecretToken); code != http.StatusOK {
+		t.Error("expect 200, got:", code)
+	}
+
+	scheduler := deleteSchedulerOf(entries[1].Bot)
+
+	if reg.GetByUsername("@Second_Bot") == nil || len(reg.List()) != 2 {
+		t.Error("unexpected registry:", reg.List())
+	}
+
+	if err := reg.Remove(context.Background(), 2); err != nil {
+		t.Error(err)
+	}
+	if err := reg.Remove(context.Background(), 2); !errors.Is(err, ErrBotNotFound) {
+		t.Error("expect ErrBotNotFound, got:", err)
+	}
+
+	if code := post(reg.WebhookPath(2), entries[1].SecretToken); code != http.StatusNotFound {
+		t.Error("expect 404 after remove, got:", code)
+	}
+	if _, ok := deleteSchedulers.Load(entries[1].Bot); ok {
+		t.Error("expect delete scheduler to be released")
+	}
+	if err := scheduler.Schedule(1, 1, time.Hour); !errors.Is(err, ErrSchedulerClosed) {
+		t.Error("expect delete scheduler to be closed")
+	}
+
+	if err := reg.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	if hits[0].Load() != 0 || hits[1].Load() != 1 {
+		t.Error("unexpected hits:", hits[0].Load(), hits[1].Load())
+	}
+}
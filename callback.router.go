@@ -0,0 +1,173 @@
+package mytgbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+type (
+	CallbackHandler func(c *UpdateContext, data *CBData) error
+
+	// CallbackRouter 按 CBData 的 Action 或完整路径分发回调
+	CallbackRouter struct {
+		mu           sync.RWMutex
+		actions      map[string]CallbackHandler
+		paths        []callbackPathRoute
+		notFound     CallbackHandler
+		notFoundText string
+		expired      CallbackHandler
+		expiredText  string
+	}
+
+	callbackPathRoute struct {
+		pattern []string
+		handler CallbackHandler
+	}
+)
+
+const (
+	defaultCallbackNotFoundText = "菜单已失效，请重新打开"
+	defaultCallbackExpiredText  = "菜单已过期，请重新打开"
+)
+
+func NewCallbackRouter() *CallbackRouter {
+	return &CallbackRouter{
+		actions:      make(map[string]CallbackHandler),
+		notFoundText: defaultCallbackNotFoundText,
+		expiredText:  defaultCallbackExpiredText,
+	}
+}
+
+// Action 按当前页面（path 末尾）注册
+func (r *CallbackRouter) Action(action string, h CallbackHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions[action] = h
+}
+
+// Path 按完整路径注册，优先于 Action；"*" 匹配一级，末尾的 "**" 匹配剩余所有级
+// 如 "settings,*,lang" 或 "shop,**"
+func (r *CallbackRouter) Path(pattern string, h CallbackHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, callbackPathRoute{pattern: strings.Split(pattern, ","), handler: h})
+}
+
+// 没有匹配或数据无法解析时调用，未设置时以 AlertCallback 提示 NotFoundText
+func (r *CallbackRouter) NotFound(h CallbackHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notFound = h
+}
+
+func (r *CallbackRouter) NotFoundText(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notFoundText = text
+}
+
+// 按钮过期时调用，data 为过期按钮的数据，可据此重新打开根菜单；
+// 未设置时以 AlertCallback 提示 ExpiredText
+func (r *CallbackRouter) Expired(h CallbackHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expired = h
+}
+
+func (r *CallbackRouter) ExpiredText(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.expiredText = text
+}
+
+// Serve 可直接注册到 Dispatcher：d.Callback("", router.Serve)
+func (r *CallbackRouter) Serve(c *UpdateContext) error {
+	cb := c.Update.CallbackQuery
+	if cb == nil {
+		return nil
+	}
+
+	var userID int64
+	if cb.From != nil {
+		userID = cb.From.ID
+	}
+
+	//签名校验失败同样视为无法处理，不会进入 handler
+	data, err := DecodeForUser(cb.Data, userID)
+	if errors.Is(err, ErrCallbackExpired) {
+		return r.onExpired(c, data, err)
+	}
+	if err != nil {
+		return r.fallback(c, data, err)
+	}
+
+	if h := r.match(data); h != nil {
+		return h(c, data)
+	}
+
+	return r.fallback(c, data, fmt.Errorf("no callback handler for %q", data.Path()))
+}
+
+func (r *CallbackRouter) match(data *CBData) CallbackHandler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	path := data.PathList()
+	for _, route := range r.paths {
+		if matchCallbackPath(route.pattern, path) {
+			return route.handler
+		}
+	}
+
+	return r.actions[data.Action()]
+}
+
+// 无法处理的回调：交给 NotFound，否则弹窗提示；data 可能为 nil
+func (r *CallbackRouter) fallback(c *UpdateContext, data *CBData, cause error) error {
+	r.mu.RLock()
+	notFound, text := r.notFound, r.notFoundText
+	r.mu.RUnlock()
+
+	if notFound != nil {
+		return notFound(c, data)
+	}
+
+	if c.Bot == nil {
+		return cause
+	}
+
+	_, _ = AlertCallback(c.Bot, c.Update.CallbackQuery.ID, text, nil)
+	return nil
+}
+
+func (r *CallbackRouter) onExpired(c *UpdateContext, data *CBData, cause error) error {
+	r.mu.RLock()
+	expired, text := r.expired, r.expiredText
+	r.mu.RUnlock()
+
+	if expired != nil {
+		return expired(c, data)
+	}
+
+	if c.Bot == nil {
+		return cause
+	}
+
+	_, _ = AlertCallback(c.Bot, c.Update.CallbackQuery.ID, text, nil)
+	return nil
+}
+
+func matchCallbackPath(pattern, path []string) bool {
+	for i, p := range pattern {
+		if p == "**" && i == len(pattern)-1 {
+			return true
+		}
+
+		if i >= len(path) || (p != "*" && p != path[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
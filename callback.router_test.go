@@ -0,0 +1,45 @@
+package mytgbot
+
+import (
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"testing"
+)
+
+func TestCallbackRouter(t *testing.T) {
+	r := NewCallbackRouter()
+
+	var got string
+	r.Action("lang", func(c *UpdateContext, data *CBData) error {
+		got = "action:" + data.Path()
+		return nil
+	})
+	r.Path("settings,*,lang", func(c *UpdateContext, data *CBData) error {
+		got = "path:" + data.GetData()[0]
+		return nil
+	})
+	r.NotFound(func(c *UpdateContext, data *CBData) error {
+		got = "not found"
+		return nil
+	})
+
+	cases := []struct {
+		data *CBData
+		want string
+	}{
+		{NewCBData("settings,user,lang", "en"), "path:en"},
+		{NewCBData("home,lang"), "action:home,lang"},
+		{NewCBData("home,unknown"), "not found"},
+	}
+
+	for _, c := range cases {
+		got = ""
+		update := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{ID: "1", Data: c.data.Encode()}}
+		if err := r.Serve(&UpdateContext{Update: update}); err != nil {
+			t.Error(err)
+		}
+
+		if got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
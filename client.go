@@ -0,0 +1,271 @@
+package mytgbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://api.telegram.org"
+	defaultTimeout = time.Second * 30
+)
+
+type (
+	// Client 基于 token 的 Bot API 客户端，所有 *ByToken 函数都经由它发起请求
+	Client struct {
+		token      string
+		baseURL    string
+		httpClient *http.Client
+		timeout    time.Duration
+		userAgent  string
+		retry      *RetryPolicy
+		limiter    *RateLimiter
+	}
+
+	ClientOption func(c *Client)
+
+	// 需要上传的文件
+	UploadFile struct {
+		Field string
+		Name  string
+		Data  []byte
+	}
+
+	// Bot API 的统一返回结构
+	apiResponse struct {
+		Ok          bool                `json:"ok"`
+		Result      json.RawMessage     `json:"result"`
+		ErrorCode   int                 `json:"error_code"`
+		Description string              `json:"description"`
+		Parameters  *ResponseParameters `json:"parameters,omitempty"`
+	}
+)
+
+var (
+	defaultClientOptsLock sync.RWMutex
+	defaultClientOpts     []ClientOption
+)
+
+// 指定 Bot API 地址，可指向本地 Bot API Server 或测试桩
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// 单次请求的超时时间，<=0 表示不设超时
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// 设置 *ByToken 系列函数所使用的默认选项
+func SetDefaultClientOptions(opts ...ClientOption) {
+	defaultClientOptsLock.Lock()
+	defer defaultClientOptsLock.Unlock()
+	defaultClientOpts = append([]ClientOption(nil), opts...)
+}
+
+func NewClient(token string, opts ...ClientOption) *Client {
+	c := &Client{
+		token:      token,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+		timeout:    defaultTimeout,
+	}
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+
+	return c
+}
+
+// 使用默认选项构造客户端
+func clientByToken(token string) *Client {
+	defaultClientOptsLock.RLock()
+	opts := defaultClientOpts
+	defaultClientOptsLock.RUnlock()
+
+	return NewClient(token, opts...)
+}
+
+func (c *Client) Token() string {
+	return c.token
+}
+
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// 生成一个共用地址与 http.Client 的 BotAPI，便于 bot 系列函数指向同一个服务
+func (c *Client) NewBotAPI() (*tgbotapi.BotAPI, error) {
+	return tgbotapi.NewBotAPIWithClient(c.token, c.baseURL+"/bot%s/%s", c.httpClient)
+}
+
+func (c *Client) methodURL(method string) string {
+	return fmt.Sprintf("%s/bot%s/%s", c.baseURL, c.token, method)
+}
+
+// Call 以表单方式调用 Bot API，result 不为 nil 时解析返回的 result 字段
+func (c *Client) Call(ctx context.Context, method string, params url.Values, result any) error {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	return c.do(ctx, method, params, func() (io.Reader, string, error) {
+		return strings.NewReader(params.Encode()), "application/x-www-form-urlencoded", nil
+	}, result)
+}
+
+// CallMultipart 以 multipart 方式调用 Bot API，用于上传文件；writeFn 可在文件字段前追加表单字段
+func (c *Client) CallMultipart(ctx context.Context, method string, params url.Values, file *UploadFile,
+	writeFn func(writer *multipart.Writer), result any) error {
+	return c.do(ctx, method, params, func() (io.Reader, string, error) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		for k, vs := range params {
+			for _, v := range vs {
+				if err := writer.WriteField(k, v); err != nil {
+					return nil, "", fmt.Errorf("failed to add %s: %w", k, err)
+				}
+			}
+		}
+
+		if writeFn != nil {
+			writeFn(writer)
+		}
+
+		if file != nil {
+			part, err := writer.CreateFormFile(file.Field, file.Name)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to create form file: %w", err)
+			}
+
+			if _, err := io.Copy(part, bytes.NewReader(file.Data)); err != nil {
+				return nil, "", fmt.Errorf("failed to copy file data: %w", err)
+			}
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to close writer: %w", err)
+		}
+
+		return body, writer.FormDataContentType(), nil
+	}, result)
+}
+
+func (c *Client) do(ctx context.Context, method string, params url.Values, bodyFn func() (io.Reader, string, error), result any) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	limited := isRateLimitedMethod(method)
+	chatID := parseChatID(params.Get("chat_id"))
+	return withRetry(ctx, c.retry, func() error {
+		if limited {
+			if err := waitRateLimit(ctx, c.limiter, chatID); err != nil {
+				return err
+			}
+		}
+		return c.doOnce(ctx, method, bodyFn, result)
+	})
+}
+
+func (c *Client) doOnce(ctx context.Context, method string, bodyFn func() (io.Reader, string, error), result any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	body, contentType, err := bodyFn()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.methodURL(method), body)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var apiResp apiResponse
+	if err := json.Unmarshal(raw, &apiResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return &APIError{
+				Method:      method,
+				StatusCode:  resp.StatusCode,
+				ErrorCode:   resp.StatusCode,
+				Description: strings.TrimSpace(string(raw)),
+			}
+		}
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if !apiResp.Ok {
+		apiErr := &APIError{
+			Method:      method,
+			StatusCode:  resp.StatusCode,
+			ErrorCode:   apiResp.ErrorCode,
+			Description: apiResp.Description,
+		}
+		if apiResp.Parameters != nil {
+			apiErr.Parameters = *apiResp.Parameters
+		}
+		return apiErr
+	}
+
+	if result != nil && len(apiResp.Result) > 0 {
+		if err := json.Unmarshal(apiResp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+
+	return nil
+}
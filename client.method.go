@@ -0,0 +1,150 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"mime/multipart"
+	"net/url"
+	"time"
+)
+
+func (c *Client) GetMe(ctx context.Context) (*BotInfo, error) {
+	var ret BotInfo
+	if err := c.Call(ctx, "getMe", nil, &ret.Result); err != nil {
+		return nil, err
+	}
+
+	ret.Ok = true
+	return &ret, nil
+}
+
+func (c *Client) GetChat(ctx context.Context, chatID int64) (*TelegramChat, error) {
+	var ret TelegramChat
+	if err := c.Call(ctx, "getChat", chatValues(chatID), &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+func (c *Client) GetUser(ctx context.Context, userID int64) (*TelegramUser, error) {
+	var ret TelegramUser
+	if err := c.Call(ctx, "getChat", chatValues(userID), &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// configFn 可增加一些参数 ：parse_mode / reply_markup
+func (c *Client) SendMessage(ctx context.Context, chatID int64, message string, configFn func(values url.Values)) (*tgbotapi.Message, error) {
+	data := chatValues(chatID)
+	data.Set("text", message)
+	if configFn != nil {
+		configFn(data)
+	}
+
+	var ret tgbotapi.Message
+	if err := c.Call(ctx, "sendMessage", data, &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// configFn 可增加一些参数 ：parse_mode / reply_markup
+func (c *Client) SendPhoto(ctx context.Context, chatID int64, photoName string, photoData []byte, caption string,
+	configFn func(writer *multipart.Writer)) (*tgbotapi.Message, error) {
+	data := chatValues(chatID)
+	if caption != "" {
+		data.Set("caption", caption)
+	}
+
+	var ret tgbotapi.Message
+	if err := c.CallMultipart(ctx, "sendPhoto", data, &UploadFile{
+		Field: "photo",
+		Name:  photoName,
+		Data:  photoData,
+	}, configFn, &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// 生成群永久链接，多次生成则新的替换旧的
+func (c *Client) ExportChatInviteLink(ctx context.Context, chatID int64) (string, error) {
+	var ret string
+	if err := c.Call(ctx, "exportChatInviteLink", chatValues(chatID), &ret); err != nil {
+		return "", err
+	}
+
+	return ret, nil
+}
+
+// 生成临时邀请链接
+func (c *Client) CreateChatInviteLink(ctx context.Context, chatID int64, name string, expireDate time.Time,
+	maxLimit int, joinCheck bool) (*InviteTempData, error) {
+	data := chatValues(chatID)
+	data.Set("name", name)
+	data.Set("expire_date", fmt.Sprintf("%d", expireDate.Unix()))
+	data.Set("member_limit", fmt.Sprintf("%d", maxLimit))
+	data.Set("creates_join_request", fmt.Sprintf("%t", joinCheck))
+
+	var ret InviteTempData
+	if err := c.Call(ctx, "createChatInviteLink", data, &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+func (c *Client) LeaveChat(ctx context.Context, chatID int64) error {
+	return c.Call(ctx, "leaveChat", chatValues(chatID), nil)
+}
+
+// 禁言，t 为 0 表示永久禁言
+func (c *Client) MuteUser(ctx context.Context, chatID int64, userID int64, t time.Duration) error {
+	data := chatUserValues(chatID, userID)
+	data.Set("can_send_messages", "false")
+	if t != 0 {
+		data.Set("until_date", fmt.Sprintf("%d", time.Now().Add(t).Unix()))
+	}
+
+	return c.Call(ctx, "restrictChatMember", data, nil)
+}
+
+func (c *Client) UnmuteUser(ctx context.Context, chatID int64, userID int64) error {
+	data := chatUserValues(chatID, userID)
+	data.Set("can_send_messages", "true")
+
+	return c.Call(ctx, "restrictChatMember", data, nil)
+}
+
+// 踢出用户，duration 为 0 表示永久封禁
+func (c *Client) KickUser(ctx context.Context, chatID int64, userID int64, duration time.Duration) error {
+	data := chatUserValues(chatID, userID)
+	if duration != 0 {
+		data.Set("until_date", fmt.Sprintf("%d", time.Now().Add(duration).Unix()))
+	}
+
+	return c.Call(ctx, "kickChatMember", data, nil)
+}
+
+// 解除封禁，若用户仍在群内也会被移出，但可重新加入
+func (c *Client) UnbanUser(ctx context.Context, chatID int64, userID int64) error {
+	return c.Call(ctx, "unbanChatMember", chatUserValues(chatID, userID), nil)
+}
+
+func chatValues(chatID int64) url.Values {
+	data := url.Values{}
+	data.Set("chat_id", fmt.Sprintf("%d", chatID))
+	return data
+}
+
+func chatUserValues(chatID int64, userID int64) url.Values {
+	data := chatValues(chatID)
+	data.Set("user_id", fmt.Sprintf("%d", userID))
+	return data
+}
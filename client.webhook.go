@@ -0,0 +1,131 @@
+package mytgbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+type (
+	// WebhookConfig setWebhook 的参数
+	WebhookConfig struct {
+		URL                string
+		SecretToken        string   //Telegram 推送时放在 X-Telegram-Bot-Api-Secret-Token 头中，见 WithSecretToken
+		AllowedUpdates     []string //nil 表示沿用之前的设置；非 nil 的空切片表示恢复 Telegram 默认（除 chat_member 等外的所有类型）
+		MaxConnections     int      //0 表示使用默认值 40
+		DropPendingUpdates bool
+		IPAddress          string
+		Certificate        []byte //自签名证书（PEM），不为空时以文件上传
+	}
+
+	// WebhookInfo getWebhookInfo 的结果
+	WebhookInfo struct {
+		URL                          string   `json:"url"`
+		HasCustomCertificate         bool     `json:"has_custom_certificate"`
+		PendingUpdateCount           int      `json:"pending_update_count"`
+		IPAddress                    string   `json:"ip_address"`
+		LastErrorDate                int64    `json:"last_error_date"`
+		LastErrorMessage             string   `json:"last_error_message"`
+		LastSynchronizationErrorDate int64    `json:"last_synchronization_error_date"`
+		MaxConnections               int      `json:"max_connections"`
+		AllowedUpdates               []string `json:"allowed_updates"`
+	}
+)
+
+func (c *Client) SetWebhook(ctx context.Context, cfg WebhookConfig) error {
+	data := url.Values{}
+	data.Set("url", cfg.URL)
+	if cfg.SecretToken != "" {
+		data.Set("secret_token", cfg.SecretToken)
+	}
+	if cfg.AllowedUpdates != nil {
+		allowed, err := json.Marshal(cfg.AllowedUpdates)
+		if err != nil {
+			return err
+		}
+		data.Set("allowed_updates", string(allowed))
+	}
+	if cfg.MaxConnections > 0 {
+		data.Set("max_connections", fmt.Sprintf("%d", cfg.MaxConnections))
+	}
+	if cfg.DropPendingUpdates {
+		data.Set("drop_pending_updates", "true")
+	}
+	if cfg.IPAddress != "" {
+		data.Set("ip_address", cfg.IPAddress)
+	}
+
+	if len(cfg.Certificate) == 0 {
+		return c.Call(ctx, "setWebhook", data, nil)
+	}
+
+	return c.CallMultipart(ctx, "setWebhook", data, &UploadFile{
+		Field: "certificate",
+		Name:  "cert.pem",
+		Data:  cfg.Certificate,
+	}, nil, nil)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, dropPendingUpdates bool) error {
+	data := url.Values{}
+	if dropPendingUpdates {
+		data.Set("drop_pending_updates", "true")
+	}
+	return c.Call(ctx, "deleteWebhook", data, nil)
+}
+
+func (c *Client) GetWebhookInfo(ctx context.Context) (*WebhookInfo, error) {
+	var ret WebhookInfo
+	if err := c.Call(ctx, "getWebhookInfo", nil, &ret); err != nil {
+		return nil, err
+	}
+
+	return &ret, nil
+}
+
+// ReconcileWebhook 启动时调用：当前设置与 cfg 不一致时重新设置，cfg.URL 为空时删除 webhook；
+// cfg.AllowedUpdates 为空表示 Telegram 默认，之前设置过的过滤会被清除。
+// 返回是否做了修改。secret_token 无法读回，更换 secret 时需直接调用 SetWebhook
+func (c *Client) ReconcileWebhook(ctx context.Context, cfg WebhookConfig) (changed bool, err error) {
+	info, err := c.GetWebhookInfo(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if cfg.URL == "" {
+		if info.URL == "" {
+			return false, nil
+		}
+		return true, c.DeleteWebhook(ctx, cfg.DropPendingUpdates)
+	}
+
+	if webhookMatches(info, cfg) {
+		return false, nil
+	}
+
+	if cfg.AllowedUpdates == nil {
+		cfg.AllowedUpdates = []string{} //显式发送空列表，否则 Telegram 沿用旧的过滤
+	}
+	return true, c.SetWebhook(ctx, cfg)
+}
+
+func webhookMatches(info *WebhookInfo, cfg WebhookConfig) bool {
+	if info.URL != cfg.URL || info.HasCustomCertificate != (len(cfg.Certificate) > 0) {
+		return false
+	}
+
+	if cfg.MaxConnections > 0 && info.MaxConnections != cfg.MaxConnections {
+		return false
+	}
+
+	if cfg.IPAddress != "" && info.IPAddress != cfg.IPAddress {
+		return false
+	}
+
+	want, got := slices.Clone(cfg.AllowedUpdates), slices.Clone(info.AllowedUpdates)
+	slices.Sort(want)
+	slices.Sort(got)
+	return slices.Equal(want, got)
+}
@@ -0,0 +1,72 @@
+package mytgbot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestReconcileWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var cert, allowed string
+	filtered := false
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, method)
+
+		switch method {
+		case "getWebhookInfo":
+			if filtered {
+				return `{"ok":true,"result":{"url":"https://old.example.com/hook","allowed_updates":["message"]}}`
+			}
+			return `{"ok":true,"result":{"url":"https://old.example.com/hook","pending_update_count":3,"last_error_message":"timeout"}}`
+		case "setWebhook":
+			allowed = r.FormValue("allowed_updates")
+			if filtered {
+				break
+			}
+			if r.FormValue("url") != "https://new.example.com/hook" || allowed != `["message","callback_query"]` {
+				return `{"ok":false,"error_code":400,"description":"Bad Request: unexpected params"}`
+			}
+			if f, _, err := r.FormFile("certificate"); err == nil {
+				data, _ := io.ReadAll(f)
+				cert = string(data)
+			}
+		}
+		return `{"ok":true,"result":true}`
+	})
+
+	c := NewClient("token", WithBaseURL(srv.URL))
+	info, err := c.GetWebhookInfo(context.Background())
+	if err != nil || info.PendingUpdateCount != 3 || info.LastErrorMessage != "timeout" {
+		t.Error("unexpected info:", info, err)
+		return
+	}
+
+	changed, err := c.ReconcileWebhook(context.Background(), WebhookConfig{
+		URL:            "https://new.example.com/hook",
+		AllowedUpdates: []string{"message", "callback_query"},
+		Certificate:    []byte("PEM"),
+	})
+	if err != nil || !changed || cert != "PEM" {
+		t.Error("unexpected reconcile:", changed, err, cert)
+	}
+
+	changed, err = c.ReconcileWebhook(context.Background(), WebhookConfig{URL: "https://old.example.com/hook"})
+	if err != nil || changed {
+		t.Error("expect unchanged:", changed, err)
+	}
+
+	//之前设置过过滤，配置中没有时需要显式重置
+	mu.Lock()
+	filtered = true
+	mu.Unlock()
+	changed, err = c.ReconcileWebhook(context.Background(), WebhookConfig{URL: "https://old.example.com/hook"})
+	if err != nil || !changed || allowed != "[]" {
+		t.Error("expect allowed_updates reset:", changed, err, allowed)
+	}
+}
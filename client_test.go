@@ -0,0 +1,76 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newStubServer(t *testing.T, handler func(method string, r *http.Request) string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseMultipartForm(1 << 20)
+		method := r.URL.Path[len("/bottoken/"):]
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(handler(method, r)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientSendMessage(t *testing.T) {
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if method != "sendMessage" || r.FormValue("chat_id") != "100" || r.FormValue("text") != "hello" {
+			return `{"ok":false,"error_code":400,"description":"Bad Request: unexpected call"}`
+		}
+		return `{"ok":true,"result":{"message_id":7,"chat":{"id":100}}}`
+	})
+
+	msg, err := NewClient("token", WithBaseURL(srv.URL)).SendMessage(context.Background(), 100, "hello", nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if msg.MessageID != 7 {
+		t.Error("unexpected message id:", msg.MessageID)
+	}
+}
+
+func TestByTokenUsesDefaultOptions(t *testing.T) {
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		return `{"ok":true,"result":{"id":-100,"type":"supergroup","title":"test"}}`
+	})
+
+	SetDefaultClientOptions(WithBaseURL(srv.URL))
+	defer SetDefaultClientOptions()
+
+	chat, err := GetChatByToken("token", -100)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if chat.Title != "test" {
+		t.Error("unexpected chat:", chat)
+	}
+}
+
+func TestClientAPIError(t *testing.T) {
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		return `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 5","parameters":{"retry_after":5}}`
+	})
+
+	_, err := NewClient("token", WithBaseURL(srv.URL)).SendMessage(context.Background(), 100, "hello", nil)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Error("expect *APIError, got:", err)
+		return
+	}
+
+	if apiErr.ErrorCode != 429 || apiErr.Parameters.RetryAfter != 5 || apiErr.Method != "sendMessage" {
+		t.Error("unexpected api error:", apiErr)
+	}
+}
@@ -0,0 +1,454 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/any-call/gobase/util/mylog"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// ConversationKey 会话按 (chat, user) 区分，同一用户在不同群里的会话互不影响
+	ConversationKey struct {
+		ChatID int64 `json:"chat_id"`
+		UserID int64 `json:"user_id"`
+	}
+
+	// ConversationState 会话当前所在的流程与步骤，Data 保存各步骤的输入（按步骤名）
+	ConversationState struct {
+		Flow     string            `json:"flow"`
+		Step     string            `json:"step"`
+		Data     map[string]string `json:"data"`
+		ExpireAt time.Time         `json:"expire_at"`
+	}
+
+	// StateStore 会话状态的存储接口，没有状态时返回 nil, nil
+	StateStore interface {
+		Get(key ConversationKey) (*ConversationState, error)
+		Set(key ConversationKey, state *ConversationState) error
+		Delete(key ConversationKey) error
+	}
+
+	// StateLister 可列出全部会话的存储，ConversationManager.Sweep/Run 用它主动处理超时
+	StateLister interface {
+		States() (map[ConversationKey]*ConversationState, error)
+	}
+
+	// ConversationStep 多步对话中的一步
+	ConversationStep struct {
+		Name     string
+		Prompt   string                                    //进入该步骤时发送的提示，为空则不发送
+		Validate func(c *UpdateContext, text string) error //校验输入，返回错误时回复错误内容并停留在该步骤
+		// Handle 输入通过校验并保存后调用，返回下一步的名称；
+		// 返回空字符串时按顺序进入下一步，返回 StepDone 时结束
+		Handle func(c *UpdateContext, state *ConversationState) (next string, err error)
+	}
+
+	// ConversationFlow 一个完整的多步对话
+	ConversationFlow struct {
+		Name     string
+		Steps    []*ConversationStep
+		Timeout  time.Duration //每一步等待输入的时间，<=0 时使用 ConversationManager.Timeout
+		OnDone   func(c *UpdateContext, state *ConversationState) error
+		OnCancel func(c *UpdateContext, state *ConversationState) error //取消或超时时调用，设置后不再回复 CancelText/TimeoutText
+	}
+
+	// ConversationManager 管理所有进行中的会话，通过 Attach 接入 Dispatcher
+	ConversationManager struct {
+		Timeout       time.Duration //默认 10 分钟，超时的会话自动取消
+		CancelCommand string        //默认 cancel
+		CancelText    string        //取消后的回复，为空则不回复
+		TimeoutText   string        //超时后的回复，为空则不回复
+
+		store    StateStore
+		mu       sync.RWMutex
+		flows    map[string]*ConversationFlow
+		expireMu sync.Mutex
+	}
+
+	memoryStateStore struct {
+		mu        sync.Mutex
+		states    map[ConversationKey]*ConversationState
+		lastSweep time.Time
+	}
+)
+
+const StepDone = "$done"
+
+const staleStateAge = time.Hour
+
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+	ErrStateListUnsupported = errors.New("state store does not implement StateLister")
+)
+
+// NewConversationManager store 为 nil 时使用内存存储；
+// 超时的会话在用户下一次发消息时由 Handle 处理，需要及时通知用户时调用 Run
+func NewConversationManager(store StateStore) *ConversationManager {
+	if store == nil {
+		store = NewMemoryStateStore()
+	}
+
+	return &ConversationManager{
+		Timeout:       10 * time.Minute,
+		CancelCommand: "cancel",
+		CancelText:    "已取消",
+		TimeoutText:   "操作超时，已取消",
+		store:         store,
+		flows:         make(map[string]*ConversationFlow),
+	}
+}
+
+func (m *ConversationManager) Register(flow *ConversationFlow) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flows[flow.Name] = flow
+}
+
+// Attach 把会话处理注册到 d，需在其它路由之前调用，进行中的会话优先处理消息
+func (m *ConversationManager) Attach(d *Dispatcher) {
+	d.Handle(m.Handle, m.Active())
+}
+
+// Active 当前消息的发送者是否有会话记录，已超时未处理的也算在内，交给 Handle 通知用户；只读，不修改会话
+func (m *ConversationManager) Active() Filter {
+	return func(c *UpdateContext) bool {
+		if c.Update.Message == nil {
+			return false
+		}
+
+		state, _ := m.store.Get(conversationKey(c))
+		return state != nil
+	}
+}
+
+// State 当前用户进行中的会话，没有或已超时时返回 nil；只读，超时的会话由 Handle 或 Sweep 删除并通知
+func (m *ConversationManager) State(c *UpdateContext) (*ConversationState, error) {
+	state, err := m.store.Get(conversationKey(c))
+	if err != nil || state == nil || state.expired(time.Now()) {
+		return nil, err
+	}
+	return state, nil
+}
+
+// Run 每隔 interval（<=0 时为 1 分钟）调用一次 Sweep，直到 ctx 结束；store 需实现 StateLister
+func (m *ConversationManager) Run(ctx context.Context, bot *tgbotapi.BotAPI, interval time.Duration) error {
+	if _, ok := m.store.(StateLister); !ok {
+		return ErrStateListUnsupported
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := m.Sweep(ctx, bot); err != nil {
+			mylog.Error(fmt.Sprintf("sweep conversations: %v", err))
+		}
+	}
+}
+
+// Sweep 删除所有已超时的会话，并调用 OnCancel 或回复 TimeoutText
+func (m *ConversationManager) Sweep(ctx context.Context, bot *tgbotapi.BotAPI) error {
+	lister, ok := m.store.(StateLister)
+	if !ok {
+		return ErrStateListUnsupported
+	}
+
+	states, err := lister.States()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var errs []error
+	for key, state := range states {
+		if !state.expired(now) {
+			continue
+		}
+
+		//没有触发的更新，按会话所在的对话与用户构造一个
+		c := NewUpdateContext(ctx, bot, tgbotapi.Update{Message: &tgbotapi.Message{
+			Chat: &tgbotapi.Chat{ID: key.ChatID},
+			From: &tgbotapi.User{ID: key.UserID},
+		}})
+		if err := m.expire(c); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Start 为当前用户开始 flow，已有的会话会被替换；data 为初始数据，可为 nil
+func (m *ConversationManager) Start(c *UpdateContext, flowName string, data map[string]string) error {
+	flow := m.flow(flowName)
+	if flow == nil || len(flow.Steps) == 0 {
+		return fmt.Errorf("conversation flow %q not found", flowName)
+	}
+
+	state := &ConversationState{Flow: flowName, Data: make(map[string]string)}
+	for k, v := range data {
+		state.Data[k] = v
+	}
+	return m.enter(c, flow, state, flow.Steps[0])
+}
+
+// Cancel 取消当前用户的会话
+func (m *ConversationManager) Cancel(c *UpdateContext) error {
+	state, err := m.State(c)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return ErrConversationNotFound
+	}
+
+	if err := m.store.Delete(conversationKey(c)); err != nil {
+		return err
+	}
+	return m.cancelled(c, state, m.CancelText)
+}
+
+// Handle 处理会话中的消息：超时通知、取消命令、输入校验、步骤跳转
+func (m *ConversationManager) Handle(c *UpdateContext) error {
+	state, err := m.store.Get(conversationKey(c))
+	if err != nil || state == nil {
+		return err
+	}
+
+	//已超时的会话删除并通知用户，这条消息不再作为输入
+	if state.expired(time.Now()) {
+		return m.expire(c)
+	}
+
+	text := strings.TrimSpace(c.Text())
+	if m.isCancel(text) {
+		return m.Cancel(c)
+	}
+
+	flow := m.flow(state.Flow)
+	step, index := flow.step(state.Step)
+	if step == nil {
+		_ = m.store.Delete(conversationKey(c))
+		return fmt.Errorf("conversation step %q of flow %q not found", state.Step, state.Flow)
+	}
+
+	if step.Validate != nil {
+		if err := step.Validate(c, text); err != nil {
+			if _, replyErr := c.Reply(err.Error(), nil); replyErr != nil {
+				return replyErr
+			}
+			return m.save(c, flow, state) //停留在该步骤并重新计时
+		}
+	}
+
+	if state.Data == nil {
+		state.Data = make(map[string]string)
+	}
+	state.Data[step.Name] = text
+
+	next := ""
+	if step.Handle != nil {
+		if next, err = step.Handle(c, state); err != nil {
+			return err
+		}
+	}
+
+	var nextStep *ConversationStep
+	switch {
+	case next == StepDone:
+	case next != "":
+		if nextStep, _ = flow.step(next); nextStep == nil {
+			return fmt.Errorf("conversation step %q of flow %q not found", next, flow.Name)
+		}
+	case index+1 < len(flow.Steps):
+		nextStep = flow.Steps[index+1]
+	}
+
+	if nextStep != nil {
+		return m.enter(c, flow, state, nextStep)
+	}
+
+	if err := m.store.Delete(conversationKey(c)); err != nil {
+		return err
+	}
+	if flow.OnDone != nil {
+		return flow.OnDone(c, state)
+	}
+	return nil
+}
+
+func (m *ConversationManager) enter(c *UpdateContext, flow *ConversationFlow, state *ConversationState, step *ConversationStep) error {
+	state.Step = step.Name
+	if err := m.save(c, flow, state); err != nil {
+		return err
+	}
+
+	if step.Prompt == "" {
+		return nil
+	}
+	_, err := c.Reply(step.Prompt, nil)
+	return err
+}
+
+func (m *ConversationManager) save(c *UpdateContext, flow *ConversationFlow, state *ConversationState) error {
+	timeout := flow.Timeout
+	if timeout <= 0 {
+		timeout = m.Timeout
+	}
+
+	state.ExpireAt = time.Time{}
+	if timeout > 0 {
+		state.ExpireAt = time.Now().Add(timeout)
+	}
+	return m.store.Set(conversationKey(c), state)
+}
+
+// 再次确认会话已超时后删除并通知，避免 Handle 与 Sweep 同时处理时重复通知
+func (m *ConversationManager) expire(c *UpdateContext) error {
+	key := conversationKey(c)
+
+	m.expireMu.Lock()
+	state, err := m.store.Get(key)
+	if err == nil && state != nil && state.expired(time.Now()) {
+		err = m.store.Delete(key)
+	} else {
+		state = nil
+	}
+	m.expireMu.Unlock()
+
+	if err != nil || state == nil {
+		return err
+	}
+	return m.cancelled(c, state, m.TimeoutText)
+}
+
+func (m *ConversationManager) cancelled(c *UpdateContext, state *ConversationState, text string) error {
+	if flow := m.flow(state.Flow); flow != nil && flow.OnCancel != nil {
+		return flow.OnCancel(c, state)
+	}
+
+	if text == "" {
+		return nil
+	}
+	_, err := c.Reply(text, nil)
+	return err
+}
+
+func (m *ConversationManager) flow(name string) *ConversationFlow {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.flows[name]
+}
+
+// 匹配 /cancel 与 /cancel@bot_name
+func (m *ConversationManager) isCancel(text string) bool {
+	if m.CancelCommand == "" || !strings.HasPrefix(text, "/") {
+		return false
+	}
+
+	cmd, _, _ := strings.Cut(strings.Fields(text)[0][1:], "@")
+	return strings.EqualFold(cmd, m.CancelCommand)
+}
+
+func (f *ConversationFlow) step(name string) (*ConversationStep, int) {
+	if f == nil {
+		return nil, -1
+	}
+
+	for i, step := range f.Steps {
+		if step.Name == name {
+			return step, i
+		}
+	}
+	return nil, -1
+}
+
+func (s *ConversationState) expired(now time.Time) bool {
+	return !s.ExpireAt.IsZero() && now.After(s.ExpireAt)
+}
+
+func conversationKey(c *UpdateContext) ConversationKey {
+	return ConversationKey{ChatID: c.ChatID(), UserID: c.UserID()}
+}
+
+func NewMemoryStateStore() StateStore {
+	return &memoryStateStore{
+		states:    make(map[ConversationKey]*ConversationState),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryStateStore) Get(key ConversationKey) (*ConversationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[key]
+	if !ok {
+		return nil, nil
+	}
+	return state.clone(), nil
+}
+
+func (s *memoryStateStore) States() (map[ConversationKey]*ConversationState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ret := make(map[ConversationKey]*ConversationState, len(s.states))
+	for key, state := range s.states {
+		ret[key] = state.clone()
+	}
+	return ret, nil
+}
+
+func (s *memoryStateStore) Set(key ConversationKey, state *ConversationState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	s.states[key] = state.clone()
+	return nil
+}
+
+func (s *memoryStateStore) Delete(key ConversationKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.states, key)
+	return nil
+}
+
+// 超时的会话由 ConversationManager 删除并通知，这里只清理超时一小时仍无人处理的记录，最多每分钟一次
+func (s *memoryStateStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+
+	for key, state := range s.states {
+		if state.expired(now.Add(-staleStateAge)) {
+			delete(s.states, key)
+		}
+	}
+}
+
+func (s *ConversationState) clone() *ConversationState {
+	ret := *s
+	ret.Data = make(map[string]string, len(s.Data))
+	for k, v := range s.Data {
+		ret.Data[k] = v
+	}
+	return &ret
+}
@@ -0,0 +1,160 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConversation(t *testing.T) {
+	var mu sync.Mutex
+	var replies []string
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if method == "getMe" {
+			return `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`
+		}
+		mu.Lock()
+		replies = append(replies, r.FormValue("text"))
+		mu.Unlock()
+		return `{"ok":true,"result":{"message_id":1,"chat":{"id":100}}}`
+	})
+
+	bot, err := NewClient("token", WithBaseURL(srv.URL)).NewBotAPI()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var done map[string]string
+	m := NewConversationManager(nil)
+	m.Register(&ConversationFlow{
+		Name: "pay",
+		Steps: []*ConversationStep{
+			{Name: "amount", Prompt: "enter amount", Validate: func(c *UpdateContext, text string) error {
+				if _, err := strconv.Atoi(text); err != nil {
+					return errors.New("invalid amount")
+				}
+				return nil
+			}},
+			{Name: "confirm", Prompt: "confirm?"},
+		},
+		OnDone: func(c *UpdateContext, state *ConversationState) error {
+			done = state.Data
+			return nil
+		},
+	})
+
+	d := NewDispatcher(bot)
+	m.Attach(d)
+	d.Command("pay", func(c *UpdateContext) error {
+		return m.Start(c, "pay", nil)
+	})
+
+	for _, text := range []string{"/pay", "abc", "100", "yes", "/pay", "/cancel"} {
+		if err := d.Dispatch(context.Background(), textUpdate(100, text)); err != nil {
+			t.Error(text, err)
+		}
+	}
+
+	if done["amount"] != "100" || done["confirm"] != "yes" {
+		t.Error("unexpected data:", done)
+	}
+
+	want := "enter amount|invalid amount|confirm?|enter amount|已取消"
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(replies, "|"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConversationTimeout(t *testing.T) {
+	var mu sync.Mutex
+	var replies []string
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if method == "getMe" {
+			return `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`
+		}
+		mu.Lock()
+		replies = append(replies, r.FormValue("chat_id")+":"+r.FormValue("text"))
+		mu.Unlock()
+		return `{"ok":true,"result":{"message_id":1,"chat":{"id":100}}}`
+	})
+
+	bot, err := NewClient("token", WithBaseURL(srv.URL)).NewBotAPI()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var cancelled []string
+	m := NewConversationManager(nil)
+	m.Timeout = 20 * time.Millisecond
+	m.Register(&ConversationFlow{Name: "ask", Steps: []*ConversationStep{{Name: "name", Prompt: "name?"}}})
+	m.Register(&ConversationFlow{
+		Name:  "pay",
+		Steps: []*ConversationStep{{Name: "amount"}},
+		OnCancel: func(c *UpdateContext, state *ConversationState) error {
+			cancelled = append(cancelled, state.Flow)
+			return nil
+		},
+	})
+
+	d := NewDispatcher(bot)
+	m.Attach(d)
+	d.Command("ask", func(c *UpdateContext) error {
+		return m.Start(c, "ask", nil)
+	})
+	d.Command("pay", func(c *UpdateContext) error {
+		return m.Start(c, "pay", nil)
+	})
+
+	for _, u := range []tgbotapi.Update{textUpdate(100, "/ask"), textUpdate(200, "/pay"), textUpdate(300, "/ask")} {
+		if err := d.Dispatch(context.Background(), u); err != nil {
+			t.Error(err)
+		}
+	}
+	time.Sleep(40 * time.Millisecond)
+
+	//过滤器只读：已超时的会话仍然匹配，但不删除也不通知
+	if !m.Active()(NewUpdateContext(context.Background(), bot, textUpdate(100, "hi"))) {
+		t.Error("expect Active to match expired conversation")
+	}
+	if state, _ := m.State(NewUpdateContext(context.Background(), bot, textUpdate(100, "hi"))); state != nil {
+		t.Error("expect nil state after timeout, got:", state)
+	}
+	mu.Lock()
+	if len(replies) != 2 {
+		t.Error("filter or State must not notify, got:", replies)
+	}
+	mu.Unlock()
+
+	//300 在 Sweep 之前发来消息，超时在 Handle 中处理
+	if err := d.Dispatch(context.Background(), textUpdate(300, "late")); err != nil {
+		t.Error(err)
+	}
+	if err := m.Sweep(context.Background(), bot); err != nil {
+		t.Error(err)
+	}
+	if err := m.Sweep(context.Background(), bot); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	slices.Sort(replies)
+	want := "100:name?|100:操作超时，已取消|300:name?|300:操作超时，已取消"
+	if got := strings.Join(replies, "|"); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(cancelled) != 1 || cancelled[0] != "pay" {
+		t.Error("expect OnCancel on timeout, got:", cancelled)
+	}
+}
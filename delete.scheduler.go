@@ -0,0 +1,458 @@
+package mytgbot
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type (
+	// DeleteTask 待删除的消息
+	DeleteTask struct {
+		ChatID    int64     `json:"chat_id"`
+		MessageID int       `json:"message_id"`
+		DeleteAt  time.Time `json:"delete_at"`
+	}
+
+	// DeleteStore 删除任务的持久化接口，重启后由 DeleteScheduler 重新加载
+	DeleteStore interface {
+		Save(task DeleteTask) error
+		Remove(chatID int64, messageID int) error
+		List() ([]DeleteTask, error)
+	}
+
+	DeleteFunc func(ctx context.Context, chatID int64, messageID int) error
+
+	// DeleteScheduler 用一个定时器按时间顺序删除消息
+	DeleteScheduler struct {
+		mu       sync.Mutex
+		store    DeleteStore
+		deleteFn DeleteFunc
+		onError  func(task DeleteTask, err error)
+		queue    deleteQueue
+		index    map[deleteKey]*deleteItem
+		wake     chan struct{}
+		done     chan struct{}
+		stopped  chan struct{}
+		closed   bool
+	}
+
+	deleteKey struct {
+		chatID    int64
+		messageID int
+	}
+
+	deleteItem struct {
+		task  DeleteTask
+		index int
+	}
+
+	deleteQueue []*deleteItem
+
+	memoryDeleteStore struct {
+		mu    sync.Mutex
+		tasks map[deleteKey]DeleteTask
+	}
+
+	fileDeleteStore struct {
+		memoryDeleteStore
+		path string
+	}
+)
+
+const deleteTimeout = 30 * time.Second //单次删除（含重试）的最长时间，避免卡住后续任务
+
+var (
+	ErrDeleteTaskNotFound = errors.New("delete task not found")
+	ErrSchedulerClosed    = errors.New("scheduler is closed")
+
+	deleteSchedulers sync.Map // *tgbotapi.BotAPI -> *DeleteScheduler
+)
+
+// NewDeleteScheduler 创建调度器并加载 store 中尚未执行的任务，store 为 nil 时使用内存存储
+func NewDeleteScheduler(deleteFn DeleteFunc, store DeleteStore) (*DeleteScheduler, error) {
+	if deleteFn == nil {
+		return nil, fmt.Errorf("delete func is nil")
+	}
+
+	if store == nil {
+		store = NewMemoryDeleteStore()
+	}
+
+	tasks, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load delete tasks: %w", err)
+	}
+
+	s := &DeleteScheduler{
+		store:    store,
+		deleteFn: deleteFn,
+		index:    make(map[deleteKey]*deleteItem),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	for _, task := range tasks {
+		s.push(task)
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// 使用 bot 删除消息
+func BotDeleteFunc(bot *tgbotapi.BotAPI) DeleteFunc {
+	return func(ctx context.Context, chatID int64, messageID int) error {
+		_, err := DelMessageContext(ctx, bot, chatID, messageID)
+		return err
+	}
+}
+
+// 为 bot 指定删除调度器，SendMessageByAutoDel 等函数会使用它
+func SetDeleteScheduler(bot *tgbotapi.BotAPI, s *DeleteScheduler) {
+	if s == nil {
+		deleteSchedulers.Delete(bot)
+		return
+	}
+	deleteSchedulers.Store(bot, s)
+}
+
+// 取 bot 对应的调度器，没有则创建一个内存调度器：任务只保存在内存中，进程重启后未执行的删除会丢失；
+// 需要持久化时先以 NewFileDeleteStore 创建调度器并调用 SetDeleteScheduler
+func deleteSchedulerOf(bot *tgbotapi.BotAPI) *DeleteScheduler {
+	if s, ok := deleteSchedulers.Load(bot); ok {
+		return s.(*DeleteScheduler)
+	}
+
+	s, _ := NewDeleteScheduler(BotDeleteFunc(bot), nil)
+	if actual, loaded := deleteSchedulers.LoadOrStore(bot, s); loaded {
+		_ = s.Close()
+		return actual.(*DeleteScheduler)
+	}
+	return s
+}
+
+// 删除失败时的回调，如权限不足、消息已不存在等
+func (s *DeleteScheduler) OnError(fn func(task DeleteTask, err error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onError = fn
+}
+
+// Schedule 在 after 之后删除消息，已存在的任务会被重新计时
+func (s *DeleteScheduler) Schedule(chatID int64, messageID int, after time.Duration) error {
+	return s.ScheduleAt(chatID, messageID, time.Now().Add(after))
+}
+
+func (s *DeleteScheduler) ScheduleAt(chatID int64, messageID int, at time.Time) error {
+	task := DeleteTask{ChatID: chatID, MessageID: messageID, DeleteAt: at}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrSchedulerClosed
+	}
+
+	if err := s.store.Save(task); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.push(task)
+	s.mu.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Reschedule 修改已存在任务的删除时间
+func (s *DeleteScheduler) Reschedule(chatID int64, messageID int, after time.Duration) error {
+	s.mu.Lock()
+	_, ok := s.index[deleteKey{chatID, messageID}]
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrDeleteTaskNotFound
+	}
+	return s.Schedule(chatID, messageID, after)
+}
+
+// Cancel 取消删除
+func (s *DeleteScheduler) Cancel(chatID int64, messageID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := deleteKey{chatID, messageID}
+	item, ok := s.index[key]
+	if !ok {
+		return ErrDeleteTaskNotFound
+	}
+
+	heap.Remove(&s.queue, item.index)
+	delete(s.index, key)
+	return s.store.Remove(chatID, messageID)
+}
+
+// 尚未执行的任务数
+func (s *DeleteScheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
+
+// Close 停止调度，未执行的任务仍保留在 store 中
+func (s *DeleteScheduler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	<-s.stopped
+	return nil
+}
+
+func (s *DeleteScheduler) push(task DeleteTask) {
+	key := deleteKey{task.ChatID, task.MessageID}
+	if item, ok := s.index[key]; ok {
+		item.task = task
+		heap.Fix(&s.queue, item.index)
+		return
+	}
+
+	item := &deleteItem{task: task}
+	heap.Push(&s.queue, item)
+	s.index[key] = item
+}
+
+func (s *DeleteScheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *DeleteScheduler) run() {
+	defer close(s.stopped)
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		for _, task := range s.popDue(time.Now()) {
+			s.execute(task)
+		}
+
+		timer.Reset(s.nextWait())
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-timer.C:
+		}
+	}
+}
+
+// 取出所有已到期的任务
+func (s *DeleteScheduler) popDue(now time.Time) []DeleteTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ret []DeleteTask
+	for len(s.queue) > 0 && !s.queue[0].task.DeleteAt.After(now) {
+		item := heap.Pop(&s.queue).(*deleteItem)
+		delete(s.index, deleteKey{item.task.ChatID, item.task.MessageID})
+		ret = append(ret, item.task)
+	}
+	return ret
+}
+
+func (s *DeleteScheduler) nextWait() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return time.Hour
+	}
+
+	wait := time.Until(s.queue[0].task.DeleteAt)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+func (s *DeleteScheduler) execute(task DeleteTask) {
+	ctx, cancel := context.WithTimeout(context.Background(), deleteTimeout)
+	err := s.deleteFn(ctx, task.ChatID, task.MessageID)
+	cancel()
+
+	s.mu.Lock()
+	onError := s.onError
+	if _, rescheduled := s.index[deleteKey{task.ChatID, task.MessageID}]; !rescheduled {
+		if rmErr := s.store.Remove(task.ChatID, task.MessageID); rmErr != nil && err == nil {
+			err = rmErr
+		}
+	}
+	s.mu.Unlock()
+
+	if err != nil && onError != nil {
+		onError(task, err)
+	}
+}
+
+func (q deleteQueue) Len() int { return len(q) }
+
+func (q deleteQueue) Less(i, j int) bool { return q[i].task.DeleteAt.Before(q[j].task.DeleteAt) }
+
+func (q deleteQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *deleteQueue) Push(x any) {
+	item := x.(*deleteItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+func (q *deleteQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+func NewMemoryDeleteStore() DeleteStore {
+	return &memoryDeleteStore{tasks: make(map[deleteKey]DeleteTask)}
+}
+
+func (m *memoryDeleteStore) Save(task DeleteTask) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tasks[deleteKey{task.ChatID, task.MessageID}] = task
+	return nil
+}
+
+func (m *memoryDeleteStore) Remove(chatID int64, messageID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.tasks, deleteKey{chatID, messageID})
+	return nil
+}
+
+func (m *memoryDeleteStore) List() ([]DeleteTask, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ret := make([]DeleteTask, 0, len(m.tasks))
+	for _, task := range m.tasks {
+		ret = append(ret, task)
+	}
+	return ret, nil
+}
+
+// NewFileDeleteStore 以 JSON 文件保存删除任务，每次变更整体写回
+func NewFileDeleteStore(path string) (DeleteStore, error) {
+	f := &fileDeleteStore{
+		memoryDeleteStore: memoryDeleteStore{tasks: make(map[deleteKey]DeleteTask)},
+		path:              path,
+	}
+
+	var tasks []DeleteTask
+	if err := readJSONFile(path, &tasks); err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		f.tasks[deleteKey{task.ChatID, task.MessageID}] = task
+	}
+	return f, nil
+}
+
+func (f *fileDeleteStore) Save(task DeleteTask) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.tasks[deleteKey{task.ChatID, task.MessageID}] = task
+	return f.flush()
+}
+
+func (f *fileDeleteStore) Remove(chatID int64, messageID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := deleteKey{chatID, messageID}
+	if _, ok := f.tasks[key]; !ok {
+		return nil
+	}
+	delete(f.tasks, key)
+	return f.flush()
+}
+
+func (f *fileDeleteStore) flush() error {
+	tasks := make([]DeleteTask, 0, len(f.tasks))
+	for _, task := range f.tasks {
+		tasks = append(tasks, task)
+	}
+	return writeJSONFile(f.path, tasks)
+}
+
+// 读取 JSON 文件，文件不存在时忽略
+func readJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// 先写临时文件再替换，避免写一半时进程退出导致文件损坏
+func writeJSONFile(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,64 @@
+package mytgbot
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeleteScheduler(t *testing.T) {
+	var mu sync.Mutex
+	var deleted []int
+	delFn := func(ctx context.Context, chatID int64, messageID int) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expect delete context with deadline")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		deleted = append(deleted, messageID)
+		return nil
+	}
+
+	path := filepath.Join(t.TempDir(), "delete.json")
+	store, err := NewFileDeleteStore(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s, err := NewDeleteScheduler(delFn, store)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	_ = s.Schedule(100, 2, time.Millisecond*40)
+	_ = s.Schedule(100, 1, time.Millisecond*20)
+	_ = s.Schedule(100, 3, time.Hour)
+	if err := s.Cancel(100, 2); err != nil {
+		t.Error(err)
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	_ = s.Close()
+
+	mu.Lock()
+	if len(deleted) != 1 || deleted[0] != 1 {
+		t.Error("unexpected deleted:", deleted)
+	}
+	mu.Unlock()
+
+	//重启后恢复未执行的任务
+	store, _ = NewFileDeleteStore(path)
+	s, _ = NewDeleteScheduler(delFn, store)
+	defer func() {
+		_ = s.Close()
+	}()
+
+	if s.Pending() != 1 {
+		t.Error("unexpected pending:", s.Pending())
+	}
+}
@@ -0,0 +1,413 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+type (
+	// UpdateContext 一次更新的处理上下文
+	UpdateContext struct {
+		ctx     context.Context
+		Bot     *tgbotapi.BotAPI
+		Update  tgbotapi.Update
+		Command string   //命令名，不含 / 与 @bot
+		Args    []string //命令参数，按空白分割
+		Payload string   //命令后的原始文本，如 /start 的深链参数
+		Matches []string //Text 正则的捕获结果
+		values  map[string]any
+	}
+
+	Handler func(c *UpdateContext) error
+
+	// Filter 按注册顺序依次判断，全部通过才执行 handler
+	Filter func(c *UpdateContext) bool
+
+	// Middleware 包装命中的 handler，可在其前后执行逻辑或直接拦截
+	Middleware func(next Handler) Handler
+
+	// Dispatcher 按注册顺序匹配更新并交给第一个命中的 handler
+	Dispatcher struct {
+		bot         *tgbotapi.BotAPI
+		mu          sync.RWMutex
+		routes      []route
+		middlewares []Middleware
+		notFound    Handler
+		onError     func(c *UpdateContext, err error)
+	}
+
+	route struct {
+		match   func(c *UpdateContext) bool
+		filters []Filter
+		handler Handler
+	}
+)
+
+func NewDispatcher(bot *tgbotapi.BotAPI) *Dispatcher {
+	return &Dispatcher{bot: bot}
+}
+
+func NewUpdateContext(ctx context.Context, bot *tgbotapi.BotAPI, update tgbotapi.Update) *UpdateContext {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &UpdateContext{ctx: ctx, Bot: bot, Update: update}
+}
+
+func (c *UpdateContext) Context() context.Context {
+	return c.ctx
+}
+
+func (c *UpdateContext) WithContext(ctx context.Context) {
+	if ctx != nil {
+		c.ctx = ctx
+	}
+}
+
+func (c *UpdateContext) Set(key string, value any) {
+	if c.values == nil {
+		c.values = make(map[string]any)
+	}
+	c.values[key] = value
+}
+
+func (c *UpdateContext) Get(key string) (any, bool) {
+	v, ok := c.values[key]
+	return v, ok
+}
+
+// 更新所属的对话，可能为 nil
+func (c *UpdateContext) Chat() *tgbotapi.Chat {
+	if chat := c.Update.FromChat(); chat != nil {
+		return chat
+	}
+
+	switch {
+	case c.Update.MyChatMember != nil:
+		return &c.Update.MyChatMember.Chat
+	case c.Update.ChatMember != nil:
+		return &c.Update.ChatMember.Chat
+	case c.Update.ChatJoinRequest != nil:
+		return &c.Update.ChatJoinRequest.Chat
+	}
+	return nil
+}
+
+// 触发更新的用户，可能为 nil
+func (c *UpdateContext) From() *tgbotapi.User {
+	if user := c.Update.SentFrom(); user != nil {
+		return user
+	}
+
+	switch {
+	case c.Update.MyChatMember != nil:
+		return &c.Update.MyChatMember.From
+	case c.Update.ChatMember != nil:
+		return &c.Update.ChatMember.From
+	case c.Update.ChatJoinRequest != nil:
+		return &c.Update.ChatJoinRequest.From
+	}
+	return nil
+}
+
+func (c *UpdateContext) ChatID() int64 {
+	return UpdateChatID(c.Update)
+}
+
+func (c *UpdateContext) UserID() int64 {
+	if user := c.From(); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+func (c *UpdateContext) String() string {
+	return fmt.Sprintf("update(%d) chat(%d) user(%d)", c.Update.UpdateID, c.ChatID(), c.UserID())
+}
+
+// 更新携带的消息：普通消息、编辑消息、频道消息或回调所在的消息
+func (c *UpdateContext) Message() *tgbotapi.Message {
+	switch {
+	case c.Update.Message != nil:
+		return c.Update.Message
+	case c.Update.EditedMessage != nil:
+		return c.Update.EditedMessage
+	case c.Update.ChannelPost != nil:
+		return c.Update.ChannelPost
+	case c.Update.EditedChannelPost != nil:
+		return c.Update.EditedChannelPost
+	case c.Update.CallbackQuery != nil:
+		return c.Update.CallbackQuery.Message
+	}
+	return nil
+}
+
+// 消息文本，没有文本时取图片等的说明
+func (c *UpdateContext) Text() string {
+	msg := c.Message()
+	if msg == nil || c.Update.CallbackQuery != nil {
+		return ""
+	}
+
+	if msg.Text != "" {
+		return msg.Text
+	}
+	return msg.Caption
+}
+
+// 回复当前对话
+func (c *UpdateContext) Reply(text string, configCb func(messageCfg *tgbotapi.MessageConfig)) (*tgbotapi.Message, error) {
+	return SendMessageContext(c.ctx, c.Bot, c.ChatID(), text, configCb)
+}
+
+// UpdateChatID 取更新所属的 chat id，没有时为 0
+func UpdateChatID(update tgbotapi.Update) int64 {
+	c := UpdateContext{Update: update}
+	if chat := c.Chat(); chat != nil {
+		return chat.ID
+	}
+	return 0
+}
+
+// 仅私聊
+func PrivateChat() Filter {
+	return func(c *UpdateContext) bool {
+		chat := c.Chat()
+		return chat != nil && chat.IsPrivate()
+	}
+}
+
+// 仅群组/超级群组
+func GroupChat() Filter {
+	return func(c *UpdateContext) bool {
+		chat := c.Chat()
+		return chat != nil && (chat.IsGroup() || chat.IsSuperGroup())
+	}
+}
+
+// 仅指定用户
+func FromUsers(userIDs ...int64) Filter {
+	return func(c *UpdateContext) bool {
+		uid := c.UserID()
+		for _, id := range userIDs {
+			if id == uid {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Command 注册命令，如 Command("start", h) 匹配 /start 与 /start@bot_name
+func (d *Dispatcher) Command(name string, h Handler, filters ...Filter) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	d.add(func(c *UpdateContext) bool {
+		cmd, payload, ok := d.parseCommand(c.Update.Message)
+		if !ok || cmd != name {
+			return false
+		}
+
+		c.Command = cmd
+		c.Payload = payload
+		c.Args = strings.Fields(payload)
+		return true
+	}, h, filters)
+}
+
+// Text 按正则匹配消息文本，捕获结果放在 c.Matches
+func (d *Dispatcher) Text(re *regexp.Regexp, h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		if c.Update.Message == nil {
+			return false
+		}
+
+		matches := re.FindStringSubmatch(c.Text())
+		if matches == nil {
+			return false
+		}
+
+		c.Matches = matches
+		return true
+	}, h, filters)
+}
+
+// Callback 匹配 callback_data 以 prefix 开头的回调，prefix 为空匹配全部
+func (d *Dispatcher) Callback(prefix string, h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.CallbackQuery != nil && strings.HasPrefix(c.Update.CallbackQuery.Data, prefix)
+	}, h, filters)
+}
+
+func (d *Dispatcher) InlineQuery(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.InlineQuery != nil
+	}, h, filters)
+}
+
+func (d *Dispatcher) ChosenInlineResult(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.ChosenInlineResult != nil
+	}, h, filters)
+}
+
+// ChatMember 群成员状态变化
+func (d *Dispatcher) ChatMember(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.ChatMember != nil
+	}, h, filters)
+}
+
+// MyChatMember 机器人自身在对话中的状态变化，如被拉入、踢出群
+func (d *Dispatcher) MyChatMember(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.MyChatMember != nil
+	}, h, filters)
+}
+
+// JoinRequest 入群申请
+func (d *Dispatcher) JoinRequest(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool {
+		return c.Update.ChatJoinRequest != nil
+	}, h, filters)
+}
+
+// Handle 只按 filters 匹配任意更新
+func (d *Dispatcher) Handle(h Handler, filters ...Filter) {
+	d.add(func(c *UpdateContext) bool { return true }, h, filters)
+}
+
+// Use 添加中间件，按添加顺序由外到内包装整个路由过程（匹配、过滤器与 handler，包括 NotFound），
+// 每个更新都会经过；只针对单个路由的守卫（如 ChatAdminOnly）用 Chain 包装该路由的 handler
+func (d *Dispatcher) Use(mws ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.middlewares = append(d.middlewares, mws...)
+}
+
+// 没有 handler 命中时调用
+func (d *Dispatcher) NotFound(h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.notFound = h
+}
+
+// handler 返回错误时调用，默认忽略
+func (d *Dispatcher) OnError(fn func(c *UpdateContext, err error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onError = fn
+}
+
+func (d *Dispatcher) add(match func(c *UpdateContext) bool, h Handler, filters []Filter) {
+	if h == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.routes = append(d.routes, route{match: match, filters: filters, handler: h})
+}
+
+// Dispatch 处理一条更新并返回 handler 的错误
+func (d *Dispatcher) Dispatch(ctx context.Context, update tgbotapi.Update) error {
+	return d.Serve(NewUpdateContext(ctx, d.bot, update))
+}
+
+// Serve 以已有的上下文处理更新，可作为 Handler 嵌入其它流程
+func (d *Dispatcher) Serve(c *UpdateContext) error {
+	d.mu.RLock()
+	routes := d.routes
+	middlewares := d.middlewares
+	notFound := d.notFound
+	onError := d.onError
+	d.mu.RUnlock()
+
+	//中间件包住整个路由过程：匹配与过滤器中的 panic 同样被 Recover 捕获，未命中的更新也会经过 Logger/Metrics
+	route := func(c *UpdateContext) error {
+		var h Handler
+		for _, r := range routes {
+			c.Command, c.Args, c.Payload, c.Matches = "", nil, "", nil
+			if r.match(c) && passFilters(c, r.filters) {
+				h = r.handler
+				break
+			}
+		}
+
+		//没有命中时清掉最后一次尝试留下的解析结果，notFound 看到的是未经路由的上下文
+		if h == nil {
+			c.Command, c.Args, c.Payload, c.Matches = "", nil, "", nil
+			h = notFound
+		}
+
+		if h == nil {
+			return nil
+		}
+		return h(c)
+	}
+
+	err := Chain(route, middlewares...)(c)
+	if err != nil && onError != nil {
+		onError(c, err)
+	}
+	return err
+}
+
+// ServeUpdate 与 WebhookHandler 的 cbFun 签名一致
+func (d *Dispatcher) ServeUpdate(update tgbotapi.Update) {
+	_ = d.Dispatch(context.Background(), update)
+}
+
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	WebhookHandler(w, r, func(update tgbotapi.Update) {
+		_ = d.Dispatch(r.Context(), update)
+	})
+}
+
+// Run 消费长轮询的更新通道，直到通道关闭或 ctx 结束
+func (d *Dispatcher) Run(ctx context.Context, updates tgbotapi.UpdatesChannel) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			_ = d.Dispatch(ctx, update)
+		}
+	}
+}
+
+func (d *Dispatcher) parseCommand(msg *tgbotapi.Message) (cmd string, payload string, ok bool) {
+	if msg == nil || !strings.HasPrefix(msg.Text, "/") {
+		return "", "", false
+	}
+
+	text := msg.Text[1:]
+	head, payload, _ := strings.Cut(text, " ")
+	if i := strings.IndexAny(head, "\n\t"); i >= 0 {
+		payload = head[i+1:] + " " + payload
+		head = head[:i]
+	}
+
+	cmd, botName, hasBot := strings.Cut(head, "@")
+	if hasBot && d.bot != nil && d.bot.Self.UserName != "" && !strings.EqualFold(botName, d.bot.Self.UserName) {
+		return "", "", false //发给其它机器人的命令
+	}
+
+	return strings.ToLower(cmd), strings.TrimSpace(payload), cmd != ""
+}
+
+func passFilters(c *UpdateContext, filters []Filter) bool {
+	for _, f := range filters {
+		if f != nil && !f(c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"regexp"
+	"testing"
+)
+
+func textUpdate(chatID int64, text string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		Text: text,
+		Chat: &tgbotapi.Chat{ID: chatID, Type: "private"},
+		From: &tgbotapi.User{ID: chatID},
+	}}
+}
+
+func TestDispatcher(t *testing.T) {
+	bot := &tgbotapi.BotAPI{Self: tgbotapi.User{UserName: "test_bot"}}
+	d := NewDispatcher(bot)
+
+	var got string
+	d.Command("start", func(c *UpdateContext) error {
+		got = "start:" + c.Payload
+		return nil
+	}, GroupChat())
+	d.Command("start", func(c *UpdateContext) error {
+		got = "private start:" + c.Args[0]
+		return nil
+	})
+	d.Text(regexp.MustCompile(`^pay (\d+)$`), func(c *UpdateContext) error {
+		got = "pay:" + c.Matches[1]
+		return nil
+	})
+	d.Callback("p:", func(c *UpdateContext) error {
+		got = "callback:" + c.Update.CallbackQuery.Data
+		return nil
+	})
+	d.Command("admin", func(c *UpdateContext) error {
+		got = "admin"
+		return nil
+	}, FromUsers(999))
+	d.NotFound(func(c *UpdateContext) error {
+		got = "not found" + c.Command + c.Payload + fmt.Sprint(c.Args, c.Matches)
+		return nil
+	})
+
+	cases := []struct {
+		update tgbotapi.Update
+		want   string
+	}{
+		{textUpdate(1, "/start@test_bot ref_42"), "private start:ref_42"},
+		{textUpdate(1, "/start@other_bot ref_42"), "not found[] []"},
+		{textUpdate(1, "pay 100"), "pay:100"},
+		{tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{Data: "p:home;d:"}}, "callback:p:home;d:"},
+		{textUpdate(1, "hello"), "not found[] []"},
+		{textUpdate(1, "/admin ban 42"), "not found[] []"}, //过滤未通过的路由不应留下解析结果
+	}
+
+	for _, c := range cases {
+		got = ""
+		if err := d.Dispatch(context.Background(), c.update); err != nil {
+			t.Error(err)
+		}
+
+		if got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
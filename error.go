@@ -0,0 +1,194 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+type (
+	ResponseParameters struct {
+		MigrateToChatID int64 `json:"migrate_to_chat_id,omitempty"` //群组已升级为超级群组后的新ID
+		RetryAfter      int   `json:"retry_after,omitempty"`        //触发限流后需等待的秒数
+	}
+
+	// APIError Bot API 返回的错误，可通过 errors.As 或 AsAPIError 取得
+	APIError struct {
+		Method      string
+		StatusCode  int //HTTP 状态码
+		ErrorCode   int
+		Description string
+		Parameters  ResponseParameters
+	}
+)
+
+func (e *APIError) Error() string {
+	if e.Method == "" {
+		return fmt.Sprintf("telegram api error(%d): %s", e.ErrorCode, e.Description)
+	}
+	return fmt.Sprintf("telegram api %s error(%d): %s", e.Method, e.ErrorCode, e.Description)
+}
+
+// 建议的等待时间，没有返回 retry_after 时为 0
+func (e *APIError) RetryAfter() time.Duration {
+	return time.Duration(e.Parameters.RetryAfter) * time.Second
+}
+
+// AsAPIError 从 err 中取出 *APIError，兼容 tgbotapi 返回的 tgbotapi.Error
+func AsAPIError(err error) (*APIError, bool) {
+	if err == nil {
+		return nil, false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr, true
+	}
+
+	var tgErr *tgbotapi.Error
+	if errors.As(err, &tgErr) {
+		return fromTgError(*tgErr), true
+	}
+
+	var tgErrV tgbotapi.Error
+	if errors.As(err, &tgErrV) {
+		return fromTgError(tgErrV), true
+	}
+
+	return nil, false
+}
+
+func fromTgError(e tgbotapi.Error) *APIError {
+	return &APIError{
+		ErrorCode:   e.Code,
+		Description: e.Message,
+		Parameters: ResponseParameters{
+			MigrateToChatID: e.MigrateToChatID,
+			RetryAfter:      e.RetryAfter,
+		},
+	}
+}
+
+// 错误描述，优先取 Bot API 的 description
+func errDescription(err error) string {
+	if apiErr, ok := AsAPIError(err); ok {
+		return apiErr.Description
+	}
+	return err.Error()
+}
+
+// description 关键字与错误状态的对应关系，按顺序匹配
+var errStatusRules = []struct {
+	text   string
+	status ErrStatus
+}{
+	{"bot was kicked", ErrStatusKicked},
+	{"bot is not a member", ErrStatusKicked},
+	{"the group chat was deleted", ErrStatusGroupDeleted},
+	{"bot was blocked by the user", ErrStatusBlocked},
+	{"bot can't initiate conversation", ErrStatusBlocked},
+	{"chat not found", ErrStatusChatNotFound},
+	{"user is deactivated", ErrStatusUserDeactivated},
+	{"not enough rights", ErrStatusNotEnoughRights},
+	{"have no rights", ErrStatusNotEnoughRights},
+	{"chat_admin_required", ErrStatusNotEnoughRights},
+	{"message to edit not found", ErrStatusMessageToEditNotFound},
+	{"message can't be deleted", ErrStatusMessageCantBeDeleted},
+	{"message to delete not found", ErrStatusMessageToDeleteNotFound},
+	{"message is not modified", ErrStatusNotModified},
+	{"too many requests", ErrStatusTooManyRequests},
+	{"group chat was upgraded to a supergroup", ErrStatusMigrated},
+}
+
+func (s ErrStatus) String() string {
+	switch s {
+	case ErrStatusNil:
+		return "nil"
+	case ErrStatusKicked:
+		return "kicked"
+	case ErrStatusGroupDeleted:
+		return "group deleted"
+	case ErrStatusBlocked:
+		return "blocked"
+	case ErrStatusChatNotFound:
+		return "chat not found"
+	case ErrStatusUserDeactivated:
+		return "user deactivated"
+	case ErrStatusNotEnoughRights:
+		return "not enough rights"
+	case ErrStatusMessageToEditNotFound:
+		return "message to edit not found"
+	case ErrStatusMessageCantBeDeleted:
+		return "message can't be deleted"
+	case ErrStatusMessageToDeleteNotFound:
+		return "message to delete not found"
+	case ErrStatusNotModified:
+		return "not modified"
+	case ErrStatusTooManyRequests:
+		return "too many requests"
+	case ErrStatusMigrated:
+		return "migrated"
+	case ErrStatusServerError:
+		return "server error"
+	case ErrStatusNetwork:
+		return "network"
+	case ErrStatusUnknown:
+		return "unknown"
+	}
+	return fmt.Sprintf("ErrStatus(%d)", int(s))
+}
+
+// 对话已不可用（被踢、群删除、被屏蔽、用户注销等），应停用该对话
+func (s ErrStatus) IsChatGone() bool {
+	switch s {
+	case ErrStatusKicked, ErrStatusGroupDeleted, ErrStatusBlocked, ErrStatusChatNotFound, ErrStatusUserDeactivated:
+		return true
+	}
+	return false
+}
+
+// 原样重试不会成功的错误
+func (s ErrStatus) IsPermanent() bool {
+	if s.IsChatGone() {
+		return true
+	}
+
+	switch s {
+	case ErrStatusNotEnoughRights, ErrStatusMessageToEditNotFound, ErrStatusMessageCantBeDeleted,
+		ErrStatusMessageToDeleteNotFound, ErrStatusNotModified, ErrStatusMigrated:
+		return true
+	}
+	return false
+}
+
+// 稍后重试可能成功的错误
+func (s ErrStatus) IsRetryable() bool {
+	switch s {
+	case ErrStatusTooManyRequests, ErrStatusServerError, ErrStatusNetwork:
+		return true
+	}
+	return false
+}
+
+// 群组升级为超级群组后的新 chat id
+func MigrateToChatID(err error) (int64, bool) {
+	if apiErr, ok := AsAPIError(err); ok && apiErr.Parameters.MigrateToChatID != 0 {
+		return apiErr.Parameters.MigrateToChatID, true
+	}
+	return 0, false
+}
+
+func isNetworkErr(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
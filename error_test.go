@@ -0,0 +1,34 @@
+package mytgbot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"testing"
+)
+
+func TestCheckErrStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status ErrStatus
+	}{
+		{nil, ErrStatusNil},
+		{&APIError{ErrorCode: 403, Description: "Forbidden: bot was blocked by the user"}, ErrStatusBlocked},
+		{&APIError{ErrorCode: 400, Description: "Bad Request: chat not found"}, ErrStatusChatNotFound},
+		{&APIError{ErrorCode: 429, Description: "Too Many Requests: retry after 3", Parameters: ResponseParameters{RetryAfter: 3}}, ErrStatusTooManyRequests},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: group chat was upgraded to a supergroup chat",
+			ResponseParameters: tgbotapi.ResponseParameters{MigrateToChatID: -100123}}, ErrStatusMigrated},
+		{fmt.Errorf("send: %w", &APIError{ErrorCode: 502, Description: "Bad Gateway"}), ErrStatusServerError},
+		{fmt.Errorf("Forbidden: bot was kicked from the supergroup chat"), ErrStatusKicked},
+		{fmt.Errorf("something else"), ErrStatusUnknown},
+	}
+
+	for _, c := range cases {
+		if got := CheckErrStatus(c.err); got != c.status {
+			t.Errorf("CheckErrStatus(%v) = %s, want %s", c.err, got, c.status)
+		}
+	}
+
+	if id, ok := MigrateToChatID(cases[4].err); !ok || id != -100123 {
+		t.Error("unexpected migrate chat id:", id)
+	}
+}
@@ -1,6 +1,7 @@
 package mytgbot
 
 import (
+	"context"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -12,6 +13,11 @@ func ImpInline() inLine {
 }
 
 func (self inLine) SendQueryResultArticle(bot *tgbotapi.BotAPI, queryID string, messageText string,
+	configFn func(inlineCfg *tgbotapi.InlineQueryResultArticle), config func(line *tgbotapi.InlineConfig)) (*tgbotapi.APIResponse, error) {
+	return self.SendQueryResultArticleContext(context.Background(), bot, queryID, messageText, configFn, config)
+}
+
+func (self inLine) SendQueryResultArticleContext(ctx context.Context, bot *tgbotapi.BotAPI, queryID string, messageText string,
 	configFn func(inlineCfg *tgbotapi.InlineQueryResultArticle), config func(line *tgbotapi.InlineConfig)) (*tgbotapi.APIResponse, error) {
 	//返回查询的卡片
 	article := tgbotapi.NewInlineQueryResultArticle(queryID, "", messageText)
@@ -29,5 +35,5 @@ func (self inLine) SendQueryResultArticle(bot *tgbotapi.BotAPI, queryID string,
 		config(&inlineConfig)
 	}
 
-	return bot.Request(inlineConfig)
+	return botWithContext(ctx, bot).Request(inlineConfig)
 }
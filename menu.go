@@ -0,0 +1,167 @@
+package mytgbot
+
+import (
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+type (
+	// MenuNode 菜单树的一个节点，ID 作为 CBData 的一级 path
+	MenuNode struct {
+		ID       string
+		Title    string                                               //在上级菜单中的按钮文字
+		Text     func(c *UpdateContext, data *CBData) (string, error) //打开节点时的消息文本，nil 时使用 Title
+		Children []*MenuNode
+		Handler  CallbackHandler //设置后点击按钮执行 Handler，不再渲染子菜单
+		Columns  int             //子菜单按钮每行个数，默认 1
+	}
+
+	// Menu 按 CBData 的 path 在菜单树中查找节点，原地编辑消息显示子菜单与返回按钮
+	Menu struct {
+		Root      *MenuNode
+		BackText  string //默认 "« 返回"
+		RootText  string //默认 "« 主菜单"
+		ParseMode string
+	}
+)
+
+func NewMenu(root *MenuNode) *Menu {
+	return &Menu{Root: root, BackText: "« 返回", RootText: "« 主菜单"}
+}
+
+// Register 把整棵菜单注册到 router
+func (m *Menu) Register(r *CallbackRouter) {
+	r.Path(m.Root.ID+",**", m.Serve)
+}
+
+// Open 发送根菜单，可注册为命令：d.Command("start", menu.Open)
+func (m *Menu) Open(c *UpdateContext) error {
+	data := NewCBData(m.Root.ID).ForUser(c.UserID())
+	text, markup, err := m.Render(c, &data)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Reply(text, func(messageCfg *tgbotapi.MessageConfig) {
+		messageCfg.ParseMode = m.ParseMode
+		if markup != nil {
+			messageCfg.ReplyMarkup = markup
+		}
+	})
+	return err
+}
+
+// Serve 处理菜单内的回调，签名与 CallbackHandler 一致；
+// 渲染子菜单时应答回调以结束按钮上的加载状态，设置了 Handler 的节点由 Handler 自行应答
+func (m *Menu) Serve(c *UpdateContext, data *CBData) error {
+	node := m.Find(data.PathList())
+	if node == nil {
+		return fmt.Errorf("menu node not found: %q", data.Path())
+	}
+
+	if node.Handler != nil {
+		return node.Handler(c, data)
+	}
+
+	if cb := c.Update.CallbackQuery; cb != nil && c.Bot != nil {
+		_, _ = botWithContext(c.Context(), c.Bot).Request(tgbotapi.NewCallback(cb.ID, ""))
+	}
+
+	text, markup, err := m.Render(c, data)
+	if err != nil {
+		return err
+	}
+
+	//内联模式发出的消息没有 Message，只能按 inline_message_id 编辑
+	if cb := c.Update.CallbackQuery; c.Message() == nil && cb != nil && cb.InlineMessageID != "" {
+		if c.Bot == nil {
+			return fmt.Errorf("bot api is nil")
+		}
+		_, err = botWithContext(c.Context(), c.Bot).Request(tgbotapi.EditMessageTextConfig{
+			BaseEdit:  tgbotapi.BaseEdit{InlineMessageID: cb.InlineMessageID, ReplyMarkup: markup},
+			Text:      text,
+			ParseMode: m.ParseMode,
+		})
+	} else {
+		_, err = EditMessageSafeContext(c.Context(), c.Bot, c.Message(), text, func(editMsgConfig *tgbotapi.EditMessageTextConfig) {
+			editMsgConfig.ParseMode = m.ParseMode
+			editMsgConfig.ReplyMarkup = markup
+		})
+	}
+	if IsErrNotModified(err) {
+		return nil
+	}
+	return err
+}
+
+// Find 按 path 查找节点，没有时返回 nil
+func (m *Menu) Find(path []string) *MenuNode {
+	if m.Root == nil || len(path) == 0 || path[0] != m.Root.ID {
+		return nil
+	}
+
+	node := m.Root
+	for _, id := range path[1:] {
+		node = node.child(id)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// Render 生成 data 所指节点的文本与键盘：子菜单按钮 + 返回/主菜单按钮
+func (m *Menu) Render(c *UpdateContext, data *CBData) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	node := m.Find(data.PathList())
+	if node == nil {
+		return "", nil, fmt.Errorf("menu node not found: %q", data.Path())
+	}
+
+	text := node.Title
+	if node.Text != nil {
+		var err error
+		if text, err = node.Text(c, data); err != nil {
+			return "", nil, err
+		}
+	}
+
+	columns := node.Columns
+	if columns <= 0 {
+		columns = 1
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, child := range node.Children {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(child.Title, data.PushByData(child.ID).Encode()))
+		if len(row) == columns {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if data.PathLen() > 1 {
+		rows = append(rows, BuildBackListRow(*data,
+			func() (string, []string) { return m.BackText, nil },
+			func() (string, []string) { return m.RootText, nil })...)
+	}
+
+	if len(rows) == 0 {
+		return text, nil, nil
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return text, &markup, nil
+}
+
+func (n *MenuNode) child(id string) *MenuNode {
+	for _, child := range n.Children {
+		if child.ID == id {
+			return child
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package mytgbot
+
+import (
+	"context"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMenu(t *testing.T) {
+	var got string
+	menu := NewMenu(&MenuNode{ID: "home", Title: "主菜单", Columns: 2, Children: []*MenuNode{
+		{ID: "settings", Title: "设置", Children: []*MenuNode{
+			{ID: "lang", Title: "语言", Handler: func(c *UpdateContext, data *CBData) error {
+				got = "lang:" + data.Path()
+				return nil
+			}},
+		}},
+		{ID: "help", Title: "帮助"},
+		{ID: "about", Title: "关于"},
+	}})
+
+	text, markup, err := menu.Render(&UpdateContext{}, NewCBData("home"))
+	if err != nil || text != "主菜单" || len(markup.InlineKeyboard) != 2 || len(markup.InlineKeyboard[0]) != 2 {
+		t.Error("unexpected root menu:", text, markup, err)
+		return
+	}
+
+	settings, err := Decode(*markup.InlineKeyboard[0][0].CallbackData)
+	if err != nil || settings.Path() != "home,settings" {
+		t.Error("unexpected child button:", settings, err)
+		return
+	}
+
+	_, markup, _ = menu.Render(&UpdateContext{}, settings)
+	if n := len(markup.InlineKeyboard); n != 2 || markup.InlineKeyboard[1][0].Text != "« 主菜单" {
+		t.Error("unexpected settings menu:", markup.InlineKeyboard)
+		return
+	}
+
+	r := NewCallbackRouter()
+	menu.Register(r)
+	update := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{ID: "1", Data: *markup.InlineKeyboard[0][0].CallbackData}}
+	if err := r.Serve(&UpdateContext{Update: update}); err != nil || got != "lang:home,settings,lang" {
+		t.Error("unexpected handler result:", got, err)
+	}
+}
+
+func TestMenuServeInline(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if method == "getMe" {
+			return `{"ok":true,"result":{"id":1,"is_bot":true,"username":"test_bot"}}`
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch method {
+		case "answerCallbackQuery":
+			calls = append(calls, method+":"+r.FormValue("callback_query_id"))
+		case "editMessageText":
+			calls = append(calls, method+":"+r.FormValue("inline_message_id")+":"+r.FormValue("text"))
+		default:
+			calls = append(calls, method)
+		}
+		return `{"ok":true,"result":true}`
+	})
+
+	bot, err := NewClient("token", WithBaseURL(srv.URL)).NewBotAPI()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	menu := NewMenu(&MenuNode{ID: "home", Title: "主菜单", Children: []*MenuNode{{ID: "help", Title: "帮助"}}})
+	update := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:              "7",
+		From:            &tgbotapi.User{ID: 100},
+		InlineMessageID: "inline-1",
+	}}
+	data := NewCBData("home").PushByData("help")
+	if err := menu.Serve(NewUpdateContext(context.Background(), bot, update), &data); err != nil {
+		t.Error(err)
+	}
+
+	if err := menu.Serve(NewUpdateContext(context.Background(), nil, update), &data); err == nil {
+		t.Error("expected error without bot")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(calls, "|"); got != "answerCallbackQuery:7|editMessageText:inline-1:帮助" {
+		t.Error("unexpected calls:", got)
+	}
+}
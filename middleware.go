@@ -0,0 +1,161 @@
+package mytgbot
+
+import (
+	"errors"
+	"fmt"
+	"github.com/any-call/gobase/util/mylog"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"runtime/debug"
+	"time"
+)
+
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Chain 用中间件包装 h，第一个在最外层；可用于单个路由：d.Command("ban", Chain(h, ChatAdminOnly(nil)))
+func Chain(h Handler, mws ...Middleware) Handler {
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i] != nil {
+			h = mws[i](h)
+		}
+	}
+	return h
+}
+
+// Recover 捕获 handler 的 panic，记录堆栈并转为错误
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					mylog.Error(fmt.Sprintf("panic in %s: %v\n%s", c, r, debug.Stack()))
+					err = fmt.Errorf("panic: %v", r)
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// Logger 记录每条更新的类型、耗时与错误
+func Logger() Middleware {
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				mylog.Info(fmt.Sprintf("%s %s cost %s, err: %v", c, UpdateType(c.Update), time.Since(start), err))
+			} else {
+				mylog.Info(fmt.Sprintf("%s %s cost %s", c, UpdateType(c.Update), time.Since(start)))
+			}
+			return err
+		}
+	}
+}
+
+// Metrics 每条更新处理完后回调 observe，可对接 Prometheus 等，按 UpdateType 区分类型
+func Metrics(observe func(c *UpdateContext, cost time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) error {
+			start := time.Now()
+			err := next(c)
+			observe(c, time.Since(start), err)
+			return err
+		}
+	}
+}
+
+// Throttle 按用户限流，每个用户每 rate.Per 最多处理 rate.Limit 条更新；
+// 超出时调用 onLimited，为 nil 则直接丢弃
+func Throttle(rate Rate, onLimited Handler) Middleware {
+	limiter := NewRateLimiter(RateLimitConfig{PrivateChat: rate})
+
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) error {
+			userID := c.UserID()
+			if userID == 0 {
+				return next(c)
+			}
+
+			if err := limiter.Wait(c.Context(), userID); err != nil {
+				if onLimited != nil {
+					return onLimited(c)
+				}
+				return nil
+			}
+			return next(c)
+		}
+	}
+}
+
+// AdminOnly 只允许指定用户，其他用户调用 denied，为 nil 时返回 ErrPermissionDenied
+func AdminOnly(denied Handler, userIDs ...int64) Middleware {
+	allowed := FromUsers(userIDs...)
+	return guard(allowed, denied)
+}
+
+// ChatAdminOnly 只允许群管理员（含群主），每次通过 getChatMember 查询；私聊不做限制。
+// 上下文没有 Bot 时无法查询，按无权限处理
+func ChatAdminOnly(denied Handler) Middleware {
+	return guard(func(c *UpdateContext) bool {
+		chat := c.Chat()
+		if chat == nil || chat.IsPrivate() {
+			return true
+		}
+		if c.Bot == nil {
+			return false
+		}
+
+		member, err := ImpGroup().GetChatMemberContext(c.Context(), c.Bot, chat.ID, c.UserID())
+		return err == nil && (member.IsAdministrator() || member.IsCreator())
+	}, denied)
+}
+
+func guard(allow Filter, denied Handler) Middleware {
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) error {
+			if allow(c) {
+				return next(c)
+			}
+
+			if denied != nil {
+				return denied(c)
+			}
+			return fmt.Errorf("%w: %s", ErrPermissionDenied, c)
+		}
+	}
+}
+
+// UpdateType 更新的类型，与 Bot API 的字段名一致，如 message、callback_query
+func UpdateType(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.EditedMessage != nil:
+		return "edited_message"
+	case update.ChannelPost != nil:
+		return "channel_post"
+	case update.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case update.InlineQuery != nil:
+		return "inline_query"
+	case update.ChosenInlineResult != nil:
+		return "chosen_inline_result"
+	case update.CallbackQuery != nil:
+		return "callback_query"
+	case update.ShippingQuery != nil:
+		return "shipping_query"
+	case update.PreCheckoutQuery != nil:
+		return "pre_checkout_query"
+	case update.Poll != nil:
+		return "poll"
+	case update.PollAnswer != nil:
+		return "poll_answer"
+	case update.MyChatMember != nil:
+		return "my_chat_member"
+	case update.ChatMember != nil:
+		return "chat_member"
+	case update.ChatJoinRequest != nil:
+		return "chat_join_request"
+	}
+	return "unknown"
+}
@@ -0,0 +1,90 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	d := NewDispatcher(nil)
+
+	var observed []string
+	d.Use(Recover(), Metrics(func(c *UpdateContext, cost time.Duration, err error) {
+		observed = append(observed, UpdateType(c.Update))
+	}))
+
+	limited := 0
+	d.Command("panic", func(c *UpdateContext) error {
+		panic("boom")
+	})
+	d.Command("ban", Chain(func(c *UpdateContext) error {
+		return nil
+	}, AdminOnly(nil, 1)))
+	d.Handle(Chain(func(c *UpdateContext) error {
+		return nil
+	}, Throttle(Rate{Limit: 2, Per: time.Minute}, func(c *UpdateContext) error {
+		limited++
+		return nil
+	})))
+
+	if err := d.Dispatch(context.Background(), textUpdate(100, "/panic")); err == nil {
+		t.Error("expect panic error")
+	}
+
+	if err := d.Dispatch(context.Background(), textUpdate(100, "/ban")); !errors.Is(err, ErrPermissionDenied) {
+		t.Error("expect ErrPermissionDenied, got:", err)
+	}
+
+	if err := d.Dispatch(context.Background(), textUpdate(1, "/ban")); err != nil {
+		t.Error(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		_ = d.Dispatch(context.Background(), textUpdate(100, "hello"))
+	}
+
+	//panic 跳过了内层的 Metrics
+	if limited != 1 || len(observed) != 5 || observed[0] != "message" {
+		t.Error("unexpected result:", limited, observed)
+	}
+}
+
+func TestChatAdminOnlyWithoutBot(t *testing.T) {
+	h := Chain(func(c *UpdateContext) error {
+		return nil
+	}, ChatAdminOnly(nil))
+
+	update := textUpdate(-100, "/ban")
+	update.Message.Chat.Type = "supergroup"
+	if err := h(NewUpdateContext(context.Background(), nil, update)); !errors.Is(err, ErrPermissionDenied) {
+		t.Error("expect ErrPermissionDenied, got:", err)
+	}
+}
+
+func TestMiddlewareWrapsRouting(t *testing.T) {
+	d := NewDispatcher(nil)
+
+	var observed int
+	d.Use(Recover(), Metrics(func(c *UpdateContext, cost time.Duration, err error) {
+		observed++
+	}))
+	d.Command("boom", func(c *UpdateContext) error {
+		return nil
+	}, func(c *UpdateContext) bool {
+		panic("filter")
+	})
+
+	if err := d.Dispatch(context.Background(), textUpdate(100, "/boom")); err == nil {
+		t.Error("expect filter panic recovered as error")
+	}
+
+	//没有 NotFound 时未命中的更新也经过中间件
+	if err := d.Dispatch(context.Background(), textUpdate(100, "hello")); err != nil {
+		t.Error(err)
+	}
+	if observed != 1 {
+		t.Error("expect unmatched update observed, got:", observed)
+	}
+}
@@ -0,0 +1,132 @@
+package mytgbot
+
+import (
+	"context"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+)
+
+type (
+	// DataSource 分页数据来源，如数据库查询
+	DataSource[T any] interface {
+		Count(ctx context.Context) (int, error)
+		Fetch(ctx context.Context, offset, limit int) ([]T, error)
+	}
+
+	// SliceSource 以内存中的切片作为数据来源：SliceSource[T](list)
+	SliceSource[T any] []T
+
+	// Paginator 按 CBData 的页码取数据并生成文本与分页键盘
+	Paginator[T any] struct {
+		Source   DataSource[T]
+		PageSize int                                                   //每页条数，默认 10
+		Header   func(page, totalPage, total int) string               //文本开头，可选
+		Item     func(index int, item T) string                        //每条的文本，index 为全局序号（从 0 开始），可选
+		Button   func(index int, item T) tgbotapi.InlineKeyboardButton //每条的按钮，可选
+		Empty    string                                                //没有数据时的文本
+		Keyboard PageKeyboard                                          //分页按钮设置，Values 为空时沿用 CBData 页码后的值
+	}
+
+	// PageResult 渲染结果，Page 为修正后的页码
+	PageResult[T any] struct {
+		Items     []T
+		Page      int
+		TotalPage int
+		Total     int
+		Text      string
+		Keyboard  [][]tgbotapi.InlineKeyboardButton
+	}
+)
+
+const defaultPageSize = 10
+
+func (s SliceSource[T]) Count(ctx context.Context) (int, error) {
+	return len(s), nil
+}
+
+func (s SliceSource[T]) Fetch(ctx context.Context, offset, limit int) ([]T, error) {
+	if offset >= len(s) {
+		return nil, nil
+	}
+	return s[offset:min(offset+limit, len(s))], nil
+}
+
+func NewPaginator[T any](source DataSource[T], pageSize int) *Paginator[T] {
+	return &Paginator[T]{Source: source, PageSize: pageSize}
+}
+
+// Render 取 data 的第一个值作为页码，超出范围时修正到第一页或最后一页
+func (p *Paginator[T]) Render(ctx context.Context, data CBData) (*PageResult[T], error) {
+	size := p.PageSize
+	if size <= 0 {
+		size = defaultPageSize
+	}
+
+	total, err := p.Source.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPage := max((total+size-1)/size, 1)
+	page := min(max(data.pageNum(), 1), totalPage)
+	offset := (page - 1) * size
+
+	var items []T
+	if total > 0 {
+		if items, err = p.Source.Fetch(ctx, offset, size); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &PageResult[T]{
+		Items:     items,
+		Page:      page,
+		TotalPage: totalPage,
+		Total:     total,
+		Text:      p.renderText(items, offset, page, totalPage, total),
+	}
+
+	opt := p.Keyboard
+	if opt.Values == nil {
+		if values := data.GetData(); len(values) > 1 {
+			opt.Values = values[1:]
+		}
+	}
+
+	if p.Button != nil {
+		res.Keyboard = BuildNumberedList(data, items, page, totalPage, func(i int, item T) tgbotapi.InlineKeyboardButton {
+			return p.Button(offset+i, item)
+		}, opt).ButtonRows
+	} else {
+		res.Keyboard = BuildNumberedList[T, string](data, nil, page, totalPage, nil, opt).ButtonRows
+	}
+
+	return res, nil
+}
+
+func (p *Paginator[T]) renderText(items []T, offset, page, totalPage, total int) string {
+	if total == 0 && p.Empty != "" {
+		return p.Empty
+	}
+
+	var lines []string
+	if p.Header != nil {
+		lines = append(lines, p.Header(page, totalPage, total))
+	}
+	if p.Item != nil {
+		for i, item := range items {
+			lines = append(lines, p.Item(offset+i, item))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Markup 供 ReplyMarkup 使用，没有按钮时为 nil
+func (r *PageResult[T]) Markup() *tgbotapi.InlineKeyboardMarkup {
+	if len(r.Keyboard) == 0 {
+		return nil
+	}
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(r.Keyboard...)
+	return &markup
+}
@@ -0,0 +1,41 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestPaginator(t *testing.T) {
+	var list []int
+	for i := 1; i <= 25; i++ {
+		list = append(list, i)
+	}
+
+	p := NewPaginator[int](SliceSource[int](list), 10)
+	p.Item = func(index int, item int) string {
+		return fmt.Sprintf("%d.%d", index+1, item)
+	}
+
+	res, err := p.Render(context.Background(), *NewCBData("list", "9", "q"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if res.Page != 3 || res.TotalPage != 3 || len(res.Items) != 5 || res.Text[:5] != "21.21" {
+		t.Errorf("unexpected result: %+v", res)
+		return
+	}
+
+	prev, err := Decode(*res.Keyboard[0][0].CallbackData)
+	if err != nil || fmt.Sprint(prev.GetData()) != "[2 q]" {
+		t.Error("unexpected prev button:", prev, err)
+	}
+
+	p.Source = SliceSource[int](nil)
+	p.Empty = "empty"
+	if res, err = p.Render(context.Background(), *NewCBData("list")); err != nil || res.Text != "empty" || res.Markup() != nil {
+		t.Errorf("unexpected empty result: %+v %v", res, err)
+	}
+}
@@ -0,0 +1,234 @@
+package mytgbot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/any-call/gobase/util/mylog"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/url"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+type (
+	// OffsetStore 保存下一次 getUpdates 的 offset，重启后从该位置继续
+	OffsetStore interface {
+		Load() (int, error)
+		Save(offset int) error
+	}
+
+	// Poller 以 getUpdates 长轮询获取更新，可替代 webhook，handler 签名与 WebhookHandler 的 cbFun 一致
+	Poller struct {
+		Timeout        time.Duration //长轮询等待时间，默认 30 秒
+		Limit          int           //每次最多获取的条数，默认 100
+		AllowedUpdates []string      //nil 表示沿用之前的设置，非 nil 的空切片表示恢复 Telegram 默认
+		Backoff        *RetryPolicy  //出错后的等待策略，只使用 BaseDelay/MaxDelay，默认 1 秒起、最多 30 秒；retry_after 总是遵守
+		RemoveWebhook  bool          //启动时删除 webhook，否则设置了 webhook 时 getUpdates 会返回 409
+		OnError        func(err error)
+
+		client  *Client
+		handler func(update tgbotapi.Update)
+		store   OffsetStore
+	}
+
+	memoryOffsetStore struct {
+		mu     sync.Mutex
+		offset int
+	}
+
+	fileOffsetStore struct {
+		mu   sync.Mutex
+		path string
+	}
+)
+
+// NewPoller store 为 nil 时使用内存存储，重启后从 Telegram 保留的未确认更新开始
+func NewPoller(client *Client, handler func(update tgbotapi.Update), store OffsetStore) *Poller {
+	if store == nil {
+		store = NewMemoryOffsetStore()
+	}
+
+	return &Poller{
+		Timeout: 30 * time.Second,
+		Limit:   100,
+		Backoff: &RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second},
+		client:  client,
+		handler: handler,
+		store:   store,
+	}
+}
+
+// Run 持续拉取并依次处理更新，直到 ctx 结束；每处理完一条即保存 offset
+func (p *Poller) Run(ctx context.Context) error {
+	offset, err := p.store.Load()
+	if err != nil {
+		return err
+	}
+
+	//单次请求的超时需要比长轮询的等待时间长
+	client := *p.client
+	client.timeout = p.Timeout + 10*time.Second
+	ctx = ContextWithRetryPolicy(ctx, NoRetry)
+
+	if p.RemoveWebhook {
+		if err := client.DeleteWebhook(ctx, false); err != nil {
+			return err
+		}
+	}
+
+	failures := 0
+	for {
+		updates, err := p.getUpdates(ctx, &client, offset)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if err != nil {
+			failures++
+			if p.OnError != nil {
+				p.OnError(err)
+			}
+			if !sleepContext(ctx, p.backoff(failures, err)) {
+				return ctx.Err()
+			}
+			continue
+		}
+		failures = 0
+
+		for _, update := range updates {
+			p.handle(update)
+
+			offset = update.UpdateID + 1
+			if err := p.store.Save(offset); err != nil && p.OnError != nil {
+				p.OnError(fmt.Errorf("save offset %d: %w", offset, err))
+			}
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// handler panic 时经 OnError 报告（未设置时写日志），offset 照常前进，避免重启后反复处理同一条更新
+func (p *Poller) handle(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("panic in update(%d): %v\n%s", update.UpdateID, r, debug.Stack())
+			if p.OnError != nil {
+				p.OnError(err)
+			} else {
+				mylog.Error(err.Error())
+			}
+		}
+	}()
+
+	if p.handler != nil {
+		p.handler(update)
+	}
+}
+
+func (p *Poller) getUpdates(ctx context.Context, client *Client, offset int) ([]tgbotapi.Update, error) {
+	data := url.Values{}
+	if offset > 0 {
+		data.Set("offset", fmt.Sprintf("%d", offset))
+	}
+	if p.Limit > 0 {
+		data.Set("limit", fmt.Sprintf("%d", p.Limit))
+	}
+	data.Set("timeout", fmt.Sprintf("%d", int(p.Timeout/time.Second)))
+	if p.AllowedUpdates != nil {
+		allowed, err := json.Marshal(p.AllowedUpdates)
+		if err != nil {
+			return nil, err
+		}
+		data.Set("allowed_updates", string(allowed))
+	}
+
+	var ret []tgbotapi.Update
+	if err := client.Call(ctx, "getUpdates", data, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// 按指数退避，不可重试的错误等待 MaxDelay；带 retry_after 时至少等待 retry_after，
+// 即使超过 MaxDelay/MaxRetryAfter，提前请求只会再次被限流
+func (p *Poller) backoff(failures int, err error) time.Duration {
+	policy := p.Backoff
+	if policy == nil {
+		policy = &RetryPolicy{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	}
+
+	wait, ok := policy.delay(min(failures, 16), err)
+	if !ok {
+		wait = policy.MaxDelay
+	}
+
+	if apiErr, ok := AsAPIError(err); ok && apiErr.RetryAfter() > wait {
+		wait = apiErr.RetryAfter()
+	}
+	return wait
+}
+
+// 等待 d，ctx 先结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func NewMemoryOffsetStore() OffsetStore {
+	return &memoryOffsetStore{}
+}
+
+func (m *memoryOffsetStore) Load() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.offset, nil
+}
+
+func (m *memoryOffsetStore) Save(offset int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.offset = offset
+	return nil
+}
+
+// NewFileOffsetStore 以 JSON 文件保存 offset
+func NewFileOffsetStore(path string) OffsetStore {
+	return &fileOffsetStore{path: path}
+}
+
+func (f *fileOffsetStore) Load() (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var v struct {
+		Offset int `json:"offset"`
+	}
+	if err := readJSONFile(f.path, &v); err != nil {
+		return 0, err
+	}
+	return v.Offset, nil
+}
+
+func (f *fileOffsetStore) Save(offset int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return writeJSONFile(f.path, struct {
+		Offset int `json:"offset"`
+	}{offset})
+}
@@ -0,0 +1,76 @@
+package mytgbot
+
+import (
+	"context"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var calls atomic.Int32
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		switch calls.Add(1) {
+		case 1:
+			if r.FormValue("offset") != "" {
+				return `{"ok":false,"error_code":400,"description":"Bad Request: unexpected offset"}`
+			}
+			return `{"ok":true,"result":[{"update_id":10},{"update_id":11}]}`
+		case 2:
+			return `{"ok":false,"error_code":502,"description":"Bad Gateway"}`
+		default:
+			if r.FormValue("offset") == "12" {
+				cancel()
+			}
+			return `{"ok":true,"result":[]}`
+		}
+	})
+
+	store := NewFileOffsetStore(filepath.Join(t.TempDir(), "offset.json"))
+	var handled []int
+	var errs int
+	p := NewPoller(NewClient("token", WithBaseURL(srv.URL)), func(update tgbotapi.Update) {
+		handled = append(handled, update.UpdateID)
+		if update.UpdateID == 10 {
+			panic("bad update") //panic 不能中断 Run，offset 照常前进
+		}
+	}, store)
+	p.Timeout = time.Second
+	p.Backoff = &RetryPolicy{BaseDelay: time.Millisecond * 10, MaxDelay: time.Millisecond * 10}
+	p.OnError = func(err error) { errs++ }
+
+	if err := p.Run(ctx); err != context.Canceled {
+		t.Error("expect context.Canceled, got:", err)
+	}
+
+	offset, _ := store.Load()
+	if len(handled) != 2 || offset != 12 || errs != 2 {
+		t.Error("unexpected result:", handled, offset, errs)
+	}
+}
+
+func TestPollerBackoff(t *testing.T) {
+	p := NewPoller(nil, nil, nil)
+	p.Backoff = &RetryPolicy{BaseDelay: time.Millisecond, MaxDelay: 10 * time.Second, MaxRetryAfter: 5 * time.Second}
+
+	limited := &APIError{ErrorCode: 429, Description: "Too Many Requests: retry after 60", Parameters: ResponseParameters{RetryAfter: 60}}
+	if wait := p.backoff(1, limited); wait != time.Minute {
+		t.Error("expect retry_after beyond MaxRetryAfter, got:", wait)
+	}
+
+	//不可重试的错误也要遵守 retry_after
+	denied := &APIError{ErrorCode: 403, Description: "Forbidden", Parameters: ResponseParameters{RetryAfter: 20}}
+	if wait := p.backoff(1, denied); wait != 20*time.Second {
+		t.Error("expect retry_after for non-retryable error, got:", wait)
+	}
+
+	if wait := p.backoff(1, &APIError{ErrorCode: 401, Description: "Unauthorized"}); wait != p.Backoff.MaxDelay {
+		t.Error("expect MaxDelay, got:", wait)
+	}
+}
@@ -0,0 +1,317 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Rate 每 Per 时间内最多 Limit 条
+	Rate struct {
+		Limit int
+		Per   time.Duration
+	}
+
+	RateLimitConfig struct {
+		Global      Rate //全局
+		PrivateChat Rate //单个私聊
+		GroupChat   Rate //单个群组/频道
+		Block       bool //true：等待到可发送；false：直接返回 ErrRateLimited
+	}
+
+	// RateLimiter 令牌桶限流器，按 chat id 区分私聊与群组，并共享一个全局桶
+	RateLimiter struct {
+		cfg       RateLimitConfig
+		mu        sync.Mutex
+		global    *bucket
+		chats     map[int64]*bucket
+		lastSweep time.Time
+	}
+
+	bucket struct {
+		tokens   float64
+		capacity float64
+		rate     float64 //每秒补充的令牌数
+		last     time.Time
+	}
+)
+
+var (
+	ErrRateLimited = errors.New("rate limited")
+
+	defaultRateLimiter atomic.Pointer[RateLimiter]
+
+	// 会产生或修改消息、需要限流的方法，Client 与 BotAPI 两条发送路径共用；
+	// sendChatAction 不产生消息，不占用额度
+	rateLimitedMethods = map[string]bool{
+		"sendMessage":             true,
+		"sendPhoto":               true,
+		"sendAudio":               true,
+		"sendDocument":            true,
+		"sendVideo":               true,
+		"sendAnimation":           true,
+		"sendVoice":               true,
+		"sendVideoNote":           true,
+		"sendMediaGroup":          true,
+		"sendLocation":            true,
+		"sendVenue":               true,
+		"sendContact":             true,
+		"sendPoll":                true,
+		"sendDice":                true,
+		"sendSticker":             true,
+		"sendInvoice":             true,
+		"sendGame":                true,
+		"copyMessage":             true,
+		"forwardMessage":          true,
+		"editMessageText":         true,
+		"editMessageCaption":      true,
+		"editMessageMedia":        true,
+		"editMessageReplyMarkup":  true,
+		"editMessageLiveLocation": true,
+		"stopMessageLiveLocation": true,
+		"stopPoll":                true,
+	}
+)
+
+// Telegram 建议的发送频率：全局 30 条/秒，私聊 1 条/秒，群组 20 条/分钟
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Global:      Rate{Limit: 30, Per: time.Second},
+		PrivateChat: Rate{Limit: 1, Per: time.Second},
+		GroupChat:   Rate{Limit: 20, Per: time.Minute},
+		Block:       true,
+	}
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	return &RateLimiter{
+		cfg:       cfg,
+		global:    newBucket(cfg.Global),
+		chats:     make(map[int64]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// 设置全局限流器，作用于发送函数及未单独配置的 Client；nil 表示关闭（默认）
+func SetDefaultRateLimiter(l *RateLimiter) {
+	defaultRateLimiter.Store(l)
+}
+
+func WithRateLimiter(l *RateLimiter) ClientOption {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// Wait 为 chatID 申请一次发送额度，chatID 为 0 时只占用全局额度
+func (l *RateLimiter) Wait(ctx context.Context, chatID int64) error {
+	if l == nil {
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		wait := l.reserve(chatID)
+		if wait <= 0 {
+			return nil
+		}
+
+		if !l.cfg.Block {
+			return fmt.Errorf("%w: chat %d, retry in %s", ErrRateLimited, chatID, wait)
+		}
+
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return fmt.Errorf("%w: chat %d, deadline exceeded", ErrRateLimited, chatID)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// 两个桶都有令牌时同时扣减并返回 0，否则返回需要等待的时间
+func (l *RateLimiter) reserve(chatID int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	var chat *bucket
+	if chatID != 0 {
+		chat = l.chats[chatID]
+		if chat == nil {
+			if chatID < 0 {
+				chat = newBucket(l.cfg.GroupChat)
+			} else {
+				chat = newBucket(l.cfg.PrivateChat)
+			}
+			if chat != nil {
+				l.chats[chatID] = chat
+			}
+		}
+	}
+
+	wait := l.global.wait(now)
+	if w := chat.wait(now); w > wait {
+		wait = w
+	}
+
+	if wait > 0 {
+		return wait
+	}
+
+	l.global.take()
+	chat.take()
+	return 0
+}
+
+// 定期清理已回满的对话桶，避免 map 无限增长
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+
+	for id, b := range l.chats {
+		b.refill(now)
+		if b.tokens >= b.capacity {
+			delete(l.chats, id)
+		}
+	}
+}
+
+func newBucket(r Rate) *bucket {
+	if r.Limit <= 0 || r.Per <= 0 {
+		return nil //不限
+	}
+
+	return &bucket{
+		tokens:   float64(r.Limit),
+		capacity: float64(r.Limit),
+		rate:     float64(r.Limit) / r.Per.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if !now.After(b.last) {
+		return
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
+
+func (b *bucket) wait(now time.Time) time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *bucket) take() {
+	if b != nil {
+		b.tokens--
+	}
+}
+
+// 发送前按 chatID 限流，优先使用 l，为 nil 时使用全局限流器
+func waitRateLimit(ctx context.Context, l *RateLimiter, chatID int64) error {
+	if l == nil {
+		l = defaultRateLimiter.Load()
+	}
+	return l.Wait(ctx, chatID)
+}
+
+func isRateLimitedMethod(method string) bool {
+	return rateLimitedMethods[method]
+}
+
+// tgbotapi.Chattable 的 method() 不可导出，按类型取方法名，未列出的类型返回空，见 botSend
+func chattableMethod(c tgbotapi.Chattable) string {
+	switch c.(type) {
+	case tgbotapi.MessageConfig:
+		return "sendMessage"
+	case tgbotapi.PhotoConfig:
+		return "sendPhoto"
+	case tgbotapi.AudioConfig:
+		return "sendAudio"
+	case tgbotapi.DocumentConfig:
+		return "sendDocument"
+	case tgbotapi.VideoConfig:
+		return "sendVideo"
+	case tgbotapi.AnimationConfig:
+		return "sendAnimation"
+	case tgbotapi.VoiceConfig:
+		return "sendVoice"
+	case tgbotapi.VideoNoteConfig:
+		return "sendVideoNote"
+	case tgbotapi.MediaGroupConfig:
+		return "sendMediaGroup"
+	case tgbotapi.LocationConfig:
+		return "sendLocation"
+	case tgbotapi.VenueConfig:
+		return "sendVenue"
+	case tgbotapi.ContactConfig:
+		return "sendContact"
+	case tgbotapi.SendPollConfig:
+		return "sendPoll"
+	case tgbotapi.DiceConfig:
+		return "sendDice"
+	case tgbotapi.StickerConfig:
+		return "sendSticker"
+	case tgbotapi.InvoiceConfig:
+		return "sendInvoice"
+	case tgbotapi.GameConfig:
+		return "sendGame"
+	case tgbotapi.ChatActionConfig:
+		return "sendChatAction"
+	case tgbotapi.CopyMessageConfig:
+		return "copyMessage"
+	case tgbotapi.ForwardConfig:
+		return "forwardMessage"
+	case tgbotapi.EditMessageTextConfig:
+		return "editMessageText"
+	case tgbotapi.EditMessageCaptionConfig:
+		return "editMessageCaption"
+	case tgbotapi.EditMessageMediaConfig:
+		return "editMessageMedia"
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return "editMessageReplyMarkup"
+	case tgbotapi.EditMessageLiveLocationConfig:
+		return "editMessageLiveLocation"
+	case tgbotapi.StopMessageLiveLocationConfig:
+		return "stopMessageLiveLocation"
+	case tgbotapi.StopPollConfig:
+		return "stopPoll"
+	}
+	return ""
+}
+
+func parseChatID(v string) int64 {
+	id, _ := strconv.ParseInt(v, 10, 64)
+	return id
+}
@@ -0,0 +1,77 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReject(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{
+		Global:      Rate{Limit: 3, Per: time.Second},
+		PrivateChat: Rate{Limit: 1, Per: time.Second},
+		GroupChat:   Rate{Limit: 2, Per: time.Minute},
+	})
+
+	ctx := context.Background()
+	if err := l.Wait(ctx, 100); err != nil {
+		t.Error(err)
+	}
+
+	if err := l.Wait(ctx, 100); !errors.Is(err, ErrRateLimited) {
+		t.Error("expect ErrRateLimited for private chat, got:", err)
+	}
+
+	if err := l.Wait(ctx, -100); err != nil {
+		t.Error(err)
+	}
+
+	if err := l.Wait(ctx, -200); err != nil {
+		t.Error(err)
+	}
+
+	//全局额度已用完
+	if err := l.Wait(ctx, -300); !errors.Is(err, ErrRateLimited) {
+		t.Error("expect ErrRateLimited for global bucket, got:", err)
+	}
+}
+
+func TestRateLimiterBlock(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{
+		PrivateChat: Rate{Limit: 1, Per: time.Millisecond * 50},
+		Block:       true,
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background(), 100); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	if cost := time.Since(start); cost < time.Millisecond*90 {
+		t.Error("limiter did not block:", cost)
+	}
+}
+
+func TestRateLimitedMethods(t *testing.T) {
+	for _, c := range []tgbotapi.Chattable{
+		tgbotapi.NewMessage(1, "hi"),
+		tgbotapi.NewEditMessageText(1, 1, "hi"),
+		tgbotapi.NewCopyMessage(1, 2, 3),
+		tgbotapi.EditMessageMediaConfig{},
+		tgbotapi.StopMessageLiveLocationConfig{},
+		tgbotapi.StopPollConfig{},
+	} {
+		if method := chattableMethod(c); !isRateLimitedMethod(method) {
+			t.Errorf("%T(%q): expect rate limited", c, method)
+		}
+	}
+
+	if isRateLimitedMethod(chattableMethod(tgbotapi.NewChatAction(1, tgbotapi.ChatTyping))) || isRateLimitedMethod("getMe") {
+		t.Error("expect sendChatAction and getMe not rate limited")
+	}
+}
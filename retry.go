@@ -0,0 +1,133 @@
+package mytgbot
+
+import (
+	"context"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// RetryPolicy 发送失败时的重试策略：429 按 retry_after 等待，5xx/网络错误按带抖动的指数退避等待
+type RetryPolicy struct {
+	MaxAttempts   int           //最多尝试次数(含第一次)，<=1 表示不重试
+	BaseDelay     time.Duration //指数退避的初始等待时间
+	MaxDelay      time.Duration //指数退避的单次等待上限，0 表示不限
+	MaxRetryAfter time.Duration //retry_after 超过该值时直接返回错误，0 表示不限
+}
+
+type retryPolicyKey struct{}
+
+var (
+	defaultRetryPolicy atomic.Pointer[RetryPolicy]
+
+	// 不重试，可用于单次调用关闭全局重试
+	NoRetry = &RetryPolicy{MaxAttempts: 1}
+)
+
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond * 500,
+		MaxDelay:    time.Second * 30,
+	}
+}
+
+// 设置全局重试策略，作用于发送/编辑函数及未单独配置的 Client；nil 表示关闭（默认）
+func SetDefaultRetryPolicy(p *RetryPolicy) {
+	defaultRetryPolicy.Store(p)
+}
+
+func WithRetryPolicy(p *RetryPolicy) ClientOption {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// 为单次调用指定重试策略，优先级高于 Client 与全局配置
+func ContextWithRetryPolicy(ctx context.Context, p *RetryPolicy) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, retryPolicyKey{}, p)
+}
+
+// 依次取 ctx、fallback、全局配置中的策略
+func retryPolicyOf(ctx context.Context, fallback *RetryPolicy) *RetryPolicy {
+	if ctx != nil {
+		if p, ok := ctx.Value(retryPolicyKey{}).(*RetryPolicy); ok && p != nil {
+			return p
+		}
+	}
+
+	if fallback != nil {
+		return fallback
+	}
+
+	return defaultRetryPolicy.Load()
+}
+
+// Do 按策略执行 fn，直到成功、遇到不可重试的错误、次数用尽或 ctx 结束
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || p == nil || attempt >= p.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		wait, ok := p.delay(attempt, err)
+		if !ok {
+			return err
+		}
+
+		if deadline, has := ctx.Deadline(); has && time.Until(deadline) < wait {
+			return err //等不到下一次了
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (p *RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	status := CheckErrStatus(err)
+	if !status.IsRetryable() {
+		return 0, false
+	}
+
+	if status == ErrStatusTooManyRequests {
+		if apiErr, ok := AsAPIError(err); ok && apiErr.RetryAfter() > 0 {
+			wait := apiErr.RetryAfter()
+			if p.MaxRetryAfter > 0 && wait > p.MaxRetryAfter {
+				return 0, false
+			}
+			return wait, true
+		}
+	}
+
+	wait := p.BaseDelay << (attempt - 1)
+	if wait <= 0 || (p.MaxDelay > 0 && wait > p.MaxDelay) {
+		wait = p.MaxDelay
+	}
+
+	if wait <= 0 {
+		return 0, true
+	}
+
+	//抖动：在 [wait/2, wait) 之间随机
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half))), true
+}
+
+// 按当前生效的策略执行 fn
+func withRetry(ctx context.Context, fallback *RetryPolicy, fn func() error) error {
+	return retryPolicyOf(ctx, fallback).Do(ctx, fn)
+}
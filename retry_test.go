@@ -0,0 +1,60 @@
+package mytgbot
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetry(t *testing.T) {
+	var calls int32
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return `{"ok":false,"error_code":502,"description":"Bad Gateway"}`
+		}
+		return `{"ok":true,"result":{"message_id":1,"chat":{"id":100}}}`
+	})
+
+	client := NewClient("token", WithBaseURL(srv.URL), WithRetryPolicy(&RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+	}))
+
+	if _, err := client.SendMessage(context.Background(), 100, "hello", nil); err != nil {
+		t.Error(err)
+		return
+	}
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Error("unexpected calls:", atomic.LoadInt32(&calls))
+	}
+
+	//单次调用关闭重试
+	atomic.StoreInt32(&calls, 0)
+	if _, err := client.SendMessage(ContextWithRetryPolicy(context.Background(), NoRetry), 100, "hello", nil); err == nil {
+		t.Error("expect error without retry")
+	}
+}
+
+func TestRetryRespectsDeadline(t *testing.T) {
+	var calls int32
+	srv := newStubServer(t, func(method string, r *http.Request) string {
+		atomic.AddInt32(&calls, 1)
+		return `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 30","parameters":{"retry_after":30}}`
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	client := NewClient("token", WithBaseURL(srv.URL), WithRetryPolicy(DefaultRetryPolicy()))
+	_, err := client.SendMessage(ctx, 100, "hello", nil)
+	if CheckErrStatus(err) != ErrStatusTooManyRequests {
+		t.Error("unexpected error:", err)
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Error("unexpected calls:", atomic.LoadInt32(&calls))
+	}
+}
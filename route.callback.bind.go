@@ -0,0 +1,188 @@
+package mytgbot
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+type cbField struct {
+	index int
+	value reflect.Value
+}
+
+// NewCBDataWith 以结构体 v 作为数据，PushStruct、SetStruct、Bind 的字段规则相同：
+// 字段以 tag 指定在 CBData 数据中的位置，如 `cb:"0"`，`cb:"-"` 表示跳过该字段；
+// 导出字段必须带 cb tag，调整字段顺序不影响编码，新增字段使用新的位置即可兼容已发出的按钮。
+// 支持 string、整数（十进制，与页码一致）、bool（编码为 1/0）
+func NewCBDataWith(path string, v any) (*CBData, error) {
+	c := NewCBData(path)
+	if err := c.SetStruct(v); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// PushStruct 同 PushByData，数据取自结构体 v
+func (c CBData) PushStruct(p string, v any) (CBData, error) {
+	values, err := structToValues(v)
+	if err != nil {
+		return c, err
+	}
+	return c.PushByData(p, values...), nil
+}
+
+func (c *CBData) SetStruct(v any) error {
+	values, err := structToValues(v)
+	if err != nil {
+		return err
+	}
+
+	c.SetData(values...)
+	return nil
+}
+
+// Bind 把数据写入结构体指针 v，数据不足时其余字段保持零值
+func (c *CBData) Bind(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("callback bind: expect pointer to struct, got %T", v)
+	}
+
+	fields, err := cbFields(rv.Elem())
+	if err != nil {
+		return err
+	}
+
+	values := c.GetData()
+	for _, field := range fields {
+		if field.index >= len(values) {
+			break
+		}
+		if err := setCBValue(field.value, values[field.index]); err != nil {
+			return fmt.Errorf("%w: field %d: %v", ErrInvalidCallbackData, field.index, err)
+		}
+	}
+	return nil
+}
+
+// BindCBData 如 BindCBData[PageArgs](data)
+func BindCBData[T any](c *CBData) (T, error) {
+	var ret T
+	err := c.Bind(&ret)
+	return ret, err
+}
+
+func structToValues(v any) ([]string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("callback data: expect struct, got %T", v)
+	}
+
+	fields, err := cbFields(rv)
+	if err != nil || len(fields) == 0 {
+		return nil, err
+	}
+
+	//未使用的位置为空字符串
+	values := make([]string, fields[len(fields)-1].index+1)
+	for _, field := range fields {
+		s, err := cbValueString(field.value)
+		if err != nil {
+			return nil, fmt.Errorf("callback data: field %d: %w", field.index, err)
+		}
+		values[field.index] = s
+	}
+	return values, nil
+}
+
+// 导出且未标记 cb:"-" 的字段，按位置排序；缺少 tag 或位置重复时返回错误
+func cbFields(rv reflect.Value) ([]cbField, error) {
+	var ret []cbField
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		tag, ok := f.Tag.Lookup("cb")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("callback data: field %s.%s missing cb tag", rt.Name(), f.Name)
+		}
+
+		index, err := strconv.Atoi(tag)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("callback data: field %s.%s invalid cb tag %q", rt.Name(), f.Name, tag)
+		}
+		ret = append(ret, cbField{index: index, value: rv.Field(i)})
+	}
+
+	slices.SortFunc(ret, func(a, b cbField) int {
+		return a.index - b.index
+	})
+	for i := 1; i < len(ret); i++ {
+		if ret[i].index == ret[i-1].index {
+			return nil, fmt.Errorf("callback data: %s duplicate cb tag %d", rt.Name(), ret[i].index)
+		}
+	}
+	return ret, nil
+}
+
+func cbValueString(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "1", nil
+		}
+		return "0", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// 空字符串视为零值
+func setCBValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	}
+
+	if s == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
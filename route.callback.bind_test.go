@@ -0,0 +1,70 @@
+package mytgbot
+
+import (
+	"errors"
+	"testing"
+)
+
+type listArgs struct {
+	Page    int    `cb:"0"`
+	Keyword string `cb:"1"`
+	UserID  int64  `cb:"3"`
+	Desc    bool   `cb:"2"`
+	Cache   string `cb:"-"`
+}
+
+func TestCBDataBind(t *testing.T) {
+	data, err := NewCBDataWith("list", listArgs{Page: 3, Keyword: "a,b", Desc: true, Cache: "skip", UserID: -100})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ret, err := Decode(data.Encode())
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	args, err := BindCBData[listArgs](ret)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if args != (listArgs{Page: 3, Keyword: "a,b", Desc: true, UserID: -100}) {
+		t.Errorf("unexpected args: %+v", args)
+	}
+
+	if _, err := BindCBData[listArgs](NewCBData("list", "x")); !errors.Is(err, ErrInvalidCallbackData) {
+		t.Error("expect ErrInvalidCallbackData, got:", err)
+	}
+
+	if got := data.GetData(); len(got) != 4 || got[0] != "3" || got[2] != "1" || got[3] != "-100" {
+		t.Error("unexpected data:", got)
+	}
+
+	//页码与结构体字段使用同样的编码
+	page := data.GotoPage(12, "k")
+	if args, err := BindCBData[listArgs](&page); err != nil || args.Page != 12 || args.Keyword != "k" {
+		t.Error("unexpected page args:", args, err)
+	}
+
+	var untagged struct {
+		Page int
+	}
+	if _, err := NewCBDataWith("list", untagged); err == nil {
+		t.Error("expect error for field without cb tag")
+	}
+	var duplicated struct {
+		A int `cb:"0"`
+		B int `cb:"0"`
+	}
+	if err := NewCBData("list").Bind(&duplicated); err == nil {
+		t.Error("expect error for duplicate cb tag")
+	}
+
+	if next := NewCBData("list").NextPageByData(); next.GetData()[0] != "1" {
+		t.Error("unexpected next page:", next.GetData())
+	}
+}
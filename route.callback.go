@@ -1,11 +1,13 @@
 package mytgbot
 
 import (
+	"errors"
 	"fmt"
 	"github.com/any-call/gobase/util/myconv"
 	"github.com/any-call/gobase/util/mylog"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"strings"
+	"time"
 )
 
 type PaginatedResult struct {
@@ -14,37 +16,60 @@ type PaginatedResult struct {
 }
 
 type CBData struct {
-	path string
-	data string
+	path   []string
+	data   []string
+	userID int64         //签名绑定的用户，见 ForUser
+	ttl    time.Duration //有效期，见 ExpireIn
 }
 
+// 当前编码版本：1;<path>;<data>[;<扩展段>...]，值中的 \ , ; 以 \ 转义；
+// 扩展段以单个字母标识类型，签名段固定在最后
+const (
+	cbVersion1   = "1"
+	cbEscapeChar = '\\'
+)
+
+var ErrInvalidCallbackData = errors.New("invalid callback data")
+
+// path 以 , 分隔多级，如 "home,settings"
 func NewCBData(path string, value ...string) *CBData {
-	m := &CBData{
-		path: path,
+	m := &CBData{}
+	if path != "" {
+		m.path = strings.Split(path, ",")
 	}
 	m.SetData(value...)
 	return m
 }
 
-// Encode
+// Encode 超过 64 字节时自动转存到 CallbackStore，转存失败时记录日志并返回原始数据
 func (c CBData) Encode() string {
-	parts := []string{
-		"p:" + c.path,
-		"d:" + c.data,
+	ret, err := c.TryEncode()
+	if err != nil {
+		mylog.Info("encode callback data failed:", err)
 	}
 
-	ret := strings.Join(parts, ";")
 	mylog.Info("encode is :", ret)
 	return ret
 }
 
+// TryEncode 同 Encode，超长且无法转存时返回 ErrCallbackDataTooLong
+func (c CBData) TryEncode() (string, error) {
+	raw := strings.Join([]string{
+		cbVersion1,
+		joinEscaped(c.path),
+		joinEscaped(c.data),
+	}, ";")
+	if seg := c.timeSegment(); seg != "" {
+		raw += ";" + seg
+	}
+
+	//转存时同一用户的同一按钮复用 token；有效期段参与 key，重新渲染不会延长旧按钮的有效期
+	return storeCallbackData(fmt.Sprintf("%s|%d", raw, c.userID), signCallbackData(raw, c.userID))
+}
+
 func (c CBData) PushByData(p string, value ...string) CBData {
 	if p != "" {
-		if c.path == "" {
-			c.path = p
-		} else {
-			c.path = c.path + "," + p
-		}
+		c.path = append(c.PathList(), p)
 	}
 
 	c.SetData(value...)
@@ -53,25 +78,25 @@ func (c CBData) PushByData(p string, value ...string) CBData {
 
 // 回退到根节点
 func (c CBData) PopRootByData(v ...string) CBData {
-	c.path = c.Root()
+	if len(c.path) > 0 {
+		c.path = c.path[:1:1]
+	}
 	c.SetData(v...)
 	return c
 }
 
 func (c CBData) PopByData(v ...string) CBData {
-	list := strings.Split(c.path, ",")
-	if len(list) > 1 {
-		c.path = strings.Join(list[:len(list)-1], ",")
+	if len(c.path) > 1 {
+		c.path = c.path[: len(c.path)-1 : len(c.path)-1]
 	}
 	c.SetData(v...)
 	return c
 }
 
 func (c CBData) PopSpecPathByData(path string, v ...string) CBData {
-	list := strings.Split(c.path, ",")
-	for i := len(list) - 1; i >= 0; i-- {
-		if list[i] == path {
-			c.path = strings.Join(list[:i+1], ",")
+	for i := len(c.path) - 1; i >= 0; i-- {
+		if c.path[i] == path {
+			c.path = c.path[: i+1 : i+1]
 			break
 		}
 	}
@@ -80,8 +105,9 @@ func (c CBData) PopSpecPathByData(path string, v ...string) CBData {
 	return c
 }
 
+// 第一个值为页码，没有数据时视为第 0 页
 func (c CBData) NextPageByData(v ...string) CBData {
-	intV, _ := myconv.StrToNum[int](c.GetData()[0])
+	intV := c.pageNum()
 	param := []string{fmt.Sprintf("%d", intV+1)}
 	param = append(param, v...)
 	c.SetData(param...)
@@ -89,7 +115,7 @@ func (c CBData) NextPageByData(v ...string) CBData {
 }
 
 func (c CBData) PrevPageByData(v ...string) CBData {
-	intV, _ := myconv.StrToNum[int](c.GetData()[0])
+	intV := c.pageNum()
 	if intV > 1 {
 		intV = intV - 1
 	} else if intV <= 0 {
@@ -101,23 +127,41 @@ func (c CBData) PrevPageByData(v ...string) CBData {
 	return c
 }
 
+// GotoPage 跳转到指定页，页码作为第一个值
+func (c CBData) GotoPage(page int, v ...string) CBData {
+	param := []string{fmt.Sprintf("%d", page)}
+	param = append(param, v...)
+	c.SetData(param...)
+	return c
+}
+
+func (c *CBData) pageNum() int {
+	if len(c.data) == 0 {
+		return 0
+	}
+	intV, _ := myconv.StrToNum[int](c.data[0])
+	return intV
+}
+
+// 值可以包含任意字符；仅有一个空字符串时视为无数据
 func (c *CBData) SetData(v ...string) {
-	c.data = strings.Join(v, ",")
+	if len(v) == 0 || (len(v) == 1 && v[0] == "") {
+		c.data = nil
+		return
+	}
+	c.data = append([]string(nil), v...)
 }
 
 func (c *CBData) GetData() []string {
-	if c.data != "" {
-		return strings.Split(c.data, ",")
-	}
-	return []string{}
+	return append([]string{}, c.data...)
 }
 
 func (c *CBData) IsEmptyData() bool {
-	return c.data == ""
+	return len(c.data) == 0
 }
 
 func (c *CBData) PathLen() int {
-	return len(strings.Split(c.path, ","))
+	return len(c.path)
 }
 
 func (c *CBData) BackButton(name string, value ...string) tgbotapi.InlineKeyboardButton {
@@ -128,40 +172,190 @@ func (c *CBData) BackToRootButton(name string, value ...string) tgbotapi.InlineK
 	return tgbotapi.NewInlineKeyboardButtonData(name, c.PopRootByData(value...).Encode())
 }
 
+func (c *CBData) Path() string {
+	return strings.Join(c.path, ",")
+}
+
+func (c *CBData) PathList() []string {
+	return append([]string{}, c.path...)
+}
+
 // 当前 Action（当前页面）= path 末尾
 func (c *CBData) Action() string {
-	if c.path == "" {
+	if len(c.path) == 0 {
 		return ""
 	}
-	parts := strings.Split(c.path, ",")
-	return parts[len(parts)-1]
+	return c.path[len(c.path)-1]
 }
 
 // 主页入口（第一个节点）
 func (c *CBData) Root() string {
-	if c.path == "" {
+	if len(c.path) == 0 {
 		return ""
 	}
-	parts := strings.Split(c.path, ",")
-	return parts[0]
+	return c.path[0]
 }
 
-// Decode 将 base64 字符串解码并解 gzip，还原为 CBData
+// Decode 还原 Encode 的结果，服务端存储的 token 会先从 CallbackStore 取回；
+// 兼容旧版 p:<path>;d:<data> 格式
 func Decode(str string) (*CBData, error) {
+	return DecodeForUser(str, 0)
+}
+
+// DecodeForUser 同 Decode，userID 为点击按钮的用户，用于校验 ForUser 绑定的签名；
+// 按钮过期时同时返回数据与 ErrCallbackExpired，便于按数据重新打开菜单
+func DecodeForUser(str string, userID int64) (*CBData, error) {
+	str, err := loadCallbackData(str)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(str, cbVersion1+";") {
+		return decodeV1(str, userID)
+	}
+
+	if strings.HasPrefix(str, "p:") {
+		if !legacyCallbackAllowed() {
+			return nil, ErrCallbackSignature
+		}
+		return decodeLegacy(str), nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown format", ErrInvalidCallbackData)
+}
+
+func decodeV1(str string, userID int64) (*CBData, error) {
+	segments, err := splitEscaped(str)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(segments) < 3 {
+		return nil, fmt.Errorf("%w: expect at least 3 segments, got %d", ErrInvalidCallbackData, len(segments))
+	}
+
+	c := &CBData{path: segments[1]}
+	c.SetData(segments[2]...)
+
+	signed, expired := false, false
+	extra := segments[3:]
+	for i, seg := range extra {
+		if len(seg) == 0 || seg[0] == "" {
+			return nil, fmt.Errorf("%w: malformed segment %d", ErrInvalidCallbackData, i+3)
+		}
+
+		tag, value := seg[0][0], seg[0][1:]
+		switch tag {
+		case cbSegTime:
+			if c.ttl, expired, err = parseTimeSegment(seg); err != nil {
+				return nil, err
+			}
+		case cbSegMAC, cbSegUserMAC:
+			if i != len(extra)-1 || len(seg) != 1 {
+				return nil, fmt.Errorf("%w: signature must be the last segment", ErrInvalidCallbackData)
+			}
+
+			var bound int64
+			if tag == cbSegUserMAC {
+				bound = userID
+				c.userID = userID
+			}
+			//签名段不含 ; ，最后一个 ; 之前即为签名内容
+			if err := verifyCallbackData(str[:strings.LastIndexByte(str, ';')], value, bound); err != nil {
+				return nil, err
+			}
+			signed = true
+		default:
+			return nil, fmt.Errorf("%w: unknown segment %q", ErrInvalidCallbackData, tag)
+		}
+	}
+
+	//设置了签名时新格式的数据必须带有效签名，否则删掉签名段即可伪造
+	if !signed && getCallbackSigner() != nil {
+		return nil, ErrCallbackSignature
+	}
+
+	if expired {
+		return c, ErrCallbackExpired
+	}
+	return c, nil
+}
+
+// 旧版格式：p:a,b;d:x,y
+func decodeLegacy(str string) *CBData {
 	c := &CBData{}
 	for _, part := range strings.Split(str, ";") {
 		kv := strings.SplitN(part, ":", 2)
-		if len(kv) != 2 {
+		if len(kv) != 2 || kv[1] == "" {
 			continue
 		}
 		switch kv[0] {
 		case "p":
-			c.path = kv[1]
+			c.path = strings.Split(kv[1], ",")
 		case "d":
-			c.data = kv[1]
+			c.data = strings.Split(kv[1], ",")
 		}
 	}
-	return c, nil
+	return c
+}
+
+func joinEscaped(list []string) string {
+	var sb strings.Builder
+	for i, v := range list {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		for j := 0; j < len(v); j++ {
+			switch v[j] {
+			case cbEscapeChar, ',', ';':
+				sb.WriteByte(cbEscapeChar)
+			}
+			sb.WriteByte(v[j])
+		}
+	}
+	return sb.String()
+}
+
+// 按未转义的 ; 分段、, 分项，空段为空列表
+func splitEscaped(str string) ([][]string, error) {
+	var segments [][]string
+	var items []string
+	var sb strings.Builder
+
+	endSegment := func() {
+		items = append(items, sb.String())
+		sb.Reset()
+		if len(items) == 1 && items[0] == "" {
+			items = nil
+		}
+		segments = append(segments, items)
+		items = nil
+	}
+
+	for i := 0; i < len(str); i++ {
+		switch ch := str[i]; ch {
+		case cbEscapeChar:
+			if i+1 >= len(str) {
+				return nil, fmt.Errorf("%w: dangling escape", ErrInvalidCallbackData)
+			}
+			i++
+			if next := str[i]; next == cbEscapeChar || next == ',' || next == ';' {
+				sb.WriteByte(next)
+			} else {
+				return nil, fmt.Errorf("%w: invalid escape \\%c", ErrInvalidCallbackData, next)
+			}
+		case ',':
+			items = append(items, sb.String())
+			sb.Reset()
+		case ';':
+			endSegment()
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	endSegment()
+
+	return segments, nil
 }
 
 func BuildBackList(data CBData,
@@ -247,3 +441,122 @@ func BuildPaginatedList[T any, V string | tgbotapi.InlineKeyboardButton](
 
 	return res
 }
+
+// PageKeyboard BuildNumberedList 的分页按钮设置
+type PageKeyboard struct {
+	Columns   int      //条目按钮每行个数，默认 1
+	Window    int      //当前页左右各显示的页码数，默认 1
+	FirstLast bool     //始终显示第一页与最后一页，中间以 … 省略
+	Indicator bool     //额外一行显示 "当前页/总页数"，点击刷新当前页
+	PrevText  string   //默认 «
+	NextText  string   //默认 »
+	Values    []string //跟在页码后的值，如筛选条件
+}
+
+// BuildNumberedList 同 BuildPaginatedList，分页按钮为页码形式：« 1 … 4 [5] 6 … 20 »，
+// 按钮数据由 data 生成（GotoPage/PrevPageByData/NextPageByData），点击 … 跳到省略部分的中间页
+func BuildNumberedList[T any, V string | tgbotapi.InlineKeyboardButton](
+	data CBData,
+	list []T,
+	currPage, totalPage int,
+	cbItemFn func(index int, item T) V,
+	opt PageKeyboard,
+) PaginatedResult {
+	var res PaginatedResult
+	if opt.Columns <= 0 {
+		opt.Columns = 1
+	}
+
+	var row []tgbotapi.InlineKeyboardButton
+	for i, item := range list {
+		switch val := any(cbItemFn(i, item)).(type) {
+		case string:
+			res.TextItems = append(res.TextItems, val)
+		case tgbotapi.InlineKeyboardButton:
+			row = append(row, val)
+			if len(row) == opt.Columns {
+				res.ButtonRows = append(res.ButtonRows, row)
+				row = nil
+			}
+		}
+	}
+	if len(row) > 0 {
+		res.ButtonRows = append(res.ButtonRows, row)
+	}
+
+	if totalPage <= 1 {
+		return res
+	}
+
+	currPage = min(max(currPage, 1), totalPage)
+	curr := data.GotoPage(currPage)
+	prevText, nextText := opt.PrevText, opt.NextText
+	if prevText == "" {
+		prevText = "«"
+	}
+	if nextText == "" {
+		nextText = "»"
+	}
+
+	var pageRow []tgbotapi.InlineKeyboardButton
+	if currPage > 1 {
+		pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData(prevText, curr.PrevPageByData(opt.Values...).Encode()))
+	}
+
+	prev := 0
+	for _, page := range pageNumbers(currPage, totalPage, opt.Window, opt.FirstLast) {
+		if page-prev > 1 && prev > 0 {
+			mid := (prev + page) / 2
+			pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData("…", data.GotoPage(mid, opt.Values...).Encode()))
+		}
+
+		text := fmt.Sprintf("%d", page)
+		if page == currPage {
+			text = "[" + text + "]"
+		}
+		pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData(text, data.GotoPage(page, opt.Values...).Encode()))
+		prev = page
+	}
+
+	if currPage < totalPage {
+		pageRow = append(pageRow, tgbotapi.NewInlineKeyboardButtonData(nextText, curr.NextPageByData(opt.Values...).Encode()))
+	}
+	res.ButtonRows = append(res.ButtonRows, pageRow)
+
+	if opt.Indicator {
+		text := fmt.Sprintf("%d/%d", currPage, totalPage)
+		res.ButtonRows = append(res.ButtonRows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, data.GotoPage(currPage, opt.Values...).Encode())))
+	}
+
+	return res
+}
+
+// 需要显示的页码，升序；只隔一页时直接显示该页而不是省略号
+func pageNumbers(curr, total, window int, firstLast bool) []int {
+	if window <= 0 {
+		window = 1
+	}
+
+	from, to := max(curr-window, 1), min(curr+window, total)
+	if firstLast {
+		if from == 3 {
+			from = 2
+		}
+		if to == total-2 {
+			to = total - 1
+		}
+	}
+
+	var ret []int
+	if firstLast && from > 1 {
+		ret = append(ret, 1)
+	}
+	for i := from; i <= to; i++ {
+		ret = append(ret, i)
+	}
+	if firstLast && to < total {
+		ret = append(ret, total)
+	}
+	return ret
+}
@@ -0,0 +1,110 @@
+package mytgbot
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	cbSegMAC     = 'm' //签名段：m<mac>
+	cbSegUserMAC = 'u' //绑定用户的签名段：u<mac>，计算时带上用户 id
+
+	defaultCallbackMACSize = 6
+)
+
+// CallbackSigner 对回调数据做 HMAC 签名，防止客户端伪造 callback_data
+type CallbackSigner struct {
+	Secret []byte
+	Size   int //截断后的 MAC 字节数，默认 6（编码后 8 个字符），范围 4~32
+	// AllowLegacyUnsigned 仍接受旧格式（p:...;d:...）的无签名按钮，仅用于迁移期间兼容已发出的键盘；
+	// 旧格式无法校验，开启后任何人都可以伪造数据，默认关闭
+	AllowLegacyUnsigned bool
+}
+
+type callbackSignerHolder struct {
+	signer *CallbackSigner
+}
+
+var (
+	ErrCallbackSignature = errors.New("callback data signature mismatch")
+
+	callbackSigner atomic.Value // callbackSignerHolder
+)
+
+// 设置回调数据签名，nil 表示关闭
+func SetCallbackSigner(s *CallbackSigner) {
+	callbackSigner.Store(callbackSignerHolder{signer: s})
+}
+
+func getCallbackSigner() *CallbackSigner {
+	h, _ := callbackSigner.Load().(callbackSignerHolder)
+	if h.signer == nil || len(h.signer.Secret) == 0 {
+		return nil
+	}
+	return h.signer
+}
+
+func (s *CallbackSigner) size() int {
+	switch {
+	case s.Size <= 0:
+		return defaultCallbackMACSize
+	case s.Size < 4:
+		return 4
+	case s.Size > sha256.Size:
+		return sha256.Size
+	}
+	return s.Size
+}
+
+func (s *CallbackSigner) mac(raw string, userID int64) string {
+	h := hmac.New(sha256.New, s.Secret)
+	if userID != 0 {
+		h.Write([]byte(strconv.FormatInt(userID, 10) + "|"))
+	}
+	h.Write([]byte(raw))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:s.size()])
+}
+
+// ForUser 绑定收到键盘的用户，签名时带上用户 id，其它用户点击将无法通过校验；
+// 未设置签名时不起作用
+func (c CBData) ForUser(userID int64) CBData {
+	c.userID = userID
+	return c
+}
+
+// 未设置签名时原样返回
+func signCallbackData(raw string, userID int64) string {
+	s := getCallbackSigner()
+	if s == nil {
+		return raw
+	}
+
+	tag := cbSegMAC
+	if userID != 0 {
+		tag = cbSegUserMAC
+	}
+	return raw + ";" + string(tag) + s.mac(raw, userID)
+}
+
+// 未设置签名时不校验
+func verifyCallbackData(raw, mac string, userID int64) error {
+	s := getCallbackSigner()
+	if s == nil {
+		return nil
+	}
+
+	if !hmac.Equal([]byte(mac), []byte(s.mac(raw, userID))) {
+		return ErrCallbackSignature
+	}
+	return nil
+}
+
+// 设置了签名时默认拒绝旧格式的数据
+func legacyCallbackAllowed() bool {
+	s := getCallbackSigner()
+	return s == nil || s.AllowLegacyUnsigned
+}
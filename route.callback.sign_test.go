@@ -0,0 +1,58 @@
+package mytgbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCallbackSigner(t *testing.T) {
+	SetCallbackSigner(&CallbackSigner{Secret: []byte("secret")})
+	defer SetCallbackSigner(nil)
+
+	str := NewCBData("order", "1001").Encode()
+	if _, err := Decode(str); err != nil {
+		t.Error(err)
+		return
+	}
+
+	forged := strings.Replace(str, "1001", "1002", 1)
+	if _, err := Decode(forged); !errors.Is(err, ErrCallbackSignature) {
+		t.Error("expect ErrCallbackSignature, got:", err)
+	}
+
+	stripped := str[:strings.LastIndexByte(str, ';')]
+	if _, err := Decode(strings.Replace(stripped, "1001", "9999", 1)); !errors.Is(err, ErrCallbackSignature) {
+		t.Error("expect stripped signature rejected, got:", err)
+	}
+
+	//旧格式无法签名，设置了签名后默认拒绝
+	if _, err := Decode("p:order;d:9999"); !errors.Is(err, ErrCallbackSignature) {
+		t.Error("expect legacy data rejected, got:", err)
+	}
+
+	SetCallbackSigner(&CallbackSigner{Secret: []byte("secret"), AllowLegacyUnsigned: true})
+	if _, err := Decode("p:order;d:1001"); err != nil {
+		t.Error("expect legacy data accepted when explicitly allowed, got:", err)
+	}
+	if _, err := Decode(stripped); !errors.Is(err, ErrCallbackSignature) {
+		t.Error("expect stripped v1 signature still rejected, got:", err)
+	}
+	SetCallbackSigner(&CallbackSigner{Secret: []byte("secret")})
+
+	bound := NewCBData("order", "1001").ForUser(42).Encode()
+	ret, err := DecodeForUser(bound, 42)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	//由解码结果生成的子按钮仍绑定同一用户
+	if _, err := DecodeForUser(ret.PushByData("detail").Encode(), 42); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := DecodeForUser(bound, 43); !errors.Is(err, ErrCallbackSignature) {
+		t.Error("expect other user rejected, got:", err)
+	}
+}
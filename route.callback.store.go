@@ -0,0 +1,194 @@
+package mytgbot
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MaxCallbackDataLen  = 64   //Telegram 对 callback_data 的长度限制（字节）
+	callbackStorePrefix = "s:" //服务端存储模式的前缀，后接 token
+
+	defaultCallbackStoreTTL     = 7 * 24 * time.Hour
+	defaultCallbackStoreEntries = 50000
+)
+
+type (
+	// CallbackStore 保存超长的回调数据，按短 token 取回；
+	// key 相同时应返回同一个 token 并以新的 data 覆盖，重复渲染同一按钮不会增加存储。
+	// token 必须不可猜测（随机生成后保存），不能只由 key 或 data 计算，否则可绕过签名直接取回数据
+	CallbackStore interface {
+		Put(key, data string) (token string, err error)
+		Get(token string) (data string, err error)
+	}
+
+	memoryCallbackStore struct {
+		mu         sync.Mutex
+		ttl        time.Duration
+		maxEntries int
+		items      map[string]*list.Element //token -> item
+		keys       map[string]string        //key -> token
+		order      *list.List               //按写入时间排序，最久未写入的在末尾
+	}
+
+	callbackStoreItem struct {
+		key      string
+		token    string
+		data     string
+		expireAt time.Time
+	}
+
+	callbackStoreHolder struct {
+		store CallbackStore
+	}
+)
+
+var (
+	ErrCallbackDataTooLong  = errors.New("callback data exceeds 64 bytes")
+	ErrCallbackDataNotFound = errors.New("callback data not found")
+
+	callbackStore atomic.Value // callbackStoreHolder
+)
+
+func init() {
+	callbackStore.Store(callbackStoreHolder{store: NewMemoryCallbackStore(defaultCallbackStoreTTL, defaultCallbackStoreEntries)})
+}
+
+// 设置超长回调数据的存储，nil 表示关闭（超长时 Encode 原样返回，TryEncode 返回错误）；
+// 默认为内存存储，保留 7 天、最多 5 万条，重启后已发出的长按钮失效
+func SetCallbackStore(store CallbackStore) {
+	callbackStore.Store(callbackStoreHolder{store: store})
+}
+
+func getCallbackStore() CallbackStore {
+	return callbackStore.Load().(callbackStoreHolder).store
+}
+
+// NewMemoryCallbackStore 内存存储，ttl 为数据保留时间，<=0 表示永久保留；
+// maxEntries 为最多保存的条数，超出时淘汰最久未写入的数据，<=0 表示不限制
+func NewMemoryCallbackStore(ttl time.Duration, maxEntries int) CallbackStore {
+	return &memoryCallbackStore{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		keys:       make(map[string]string),
+		order:      list.New(),
+	}
+}
+
+func (m *memoryCallbackStore) Put(key, data string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.sweep(now)
+
+	item := callbackStoreItem{key: key, data: data}
+	if m.ttl > 0 {
+		item.expireAt = now.Add(m.ttl)
+	}
+
+	if token, ok := m.keys[key]; ok {
+		item.token = token
+		e := m.items[token]
+		e.Value = item
+		m.order.MoveToFront(e)
+		return token, nil
+	}
+
+	token, err := newCallbackToken()
+	if err != nil {
+		return "", err
+	}
+	item.token = token
+	m.keys[key] = token
+	m.items[token] = m.order.PushFront(item)
+	if m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.remove(m.order.Back())
+	}
+	return token, nil
+}
+
+func (m *memoryCallbackStore) Get(token string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.items[token]
+	if !ok {
+		return "", ErrCallbackDataNotFound
+	}
+
+	item := e.Value.(callbackStoreItem)
+	if !item.expireAt.IsZero() && time.Now().After(item.expireAt) {
+		return "", ErrCallbackDataNotFound
+	}
+	return item.data, nil
+}
+
+// 保留时间相同，按写入时间排序即按过期时间排序，从末尾删除已过期的数据
+func (m *memoryCallbackStore) sweep(now time.Time) {
+	if m.ttl <= 0 {
+		return
+	}
+
+	for e := m.order.Back(); e != nil && now.After(e.Value.(callbackStoreItem).expireAt); e = m.order.Back() {
+		m.remove(e)
+	}
+}
+
+func (m *memoryCallbackStore) remove(e *list.Element) {
+	item := e.Value.(callbackStoreItem)
+	m.order.Remove(e)
+	delete(m.items, item.token)
+	delete(m.keys, item.key)
+}
+
+// 12 字节随机数作为 token（16 个字符）
+func newCallbackToken() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// 超长时以 key 转存到 store，返回带前缀的 token
+func storeCallbackData(key, raw string) (string, error) {
+	if len(raw) <= MaxCallbackDataLen {
+		return raw, nil
+	}
+
+	store := getCallbackStore()
+	if store == nil {
+		return raw, ErrCallbackDataTooLong
+	}
+
+	token, err := store.Put(key, raw)
+	if err != nil {
+		return raw, err
+	}
+
+	ret := callbackStorePrefix + token
+	if len(ret) > MaxCallbackDataLen {
+		return raw, ErrCallbackDataTooLong
+	}
+	return ret, nil
+}
+
+// 还原 token 指向的原始数据，非 token 时原样返回
+func loadCallbackData(str string) (string, error) {
+	if len(str) <= len(callbackStorePrefix) || str[:len(callbackStorePrefix)] != callbackStorePrefix {
+		return str, nil
+	}
+
+	store := getCallbackStore()
+	if store == nil {
+		return "", ErrCallbackDataNotFound
+	}
+	return store.Get(str[len(callbackStorePrefix):])
+}
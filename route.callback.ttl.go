@@ -0,0 +1,65 @@
+package mytgbot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const cbSegTime = 't' //有效期段：t<签发时间>,<有效秒数>，与页码、结构体字段一样使用十进制
+
+var (
+	ErrCallbackExpired = errors.New("callback data expired")
+
+	callbackTTL atomic.Int64 // time.Duration
+)
+
+// 设置所有回调按钮默认的有效期，<=0 表示永久有效；单个按钮可用 ExpireIn 覆盖。
+// 有效期段位于签名段之前，只有 SetCallbackSigner 之后才受签名保护；
+// 未设置签名时客户端可以改写或删掉有效期，此时仅能防止误点旧按钮，不能作为安全限制
+func SetCallbackTTL(ttl time.Duration) {
+	callbackTTL.Store(int64(ttl))
+}
+
+// ExpireIn 按钮在 Encode 之后 ttl 时间内有效，<=0 时使用 SetCallbackTTL 的设置；
+// 由解码结果生成的子按钮沿用同样的有效期；需配合签名才能防篡改，见 SetCallbackTTL
+func (c CBData) ExpireIn(ttl time.Duration) CBData {
+	c.ttl = ttl
+	return c
+}
+
+func (c *CBData) effectiveTTL() time.Duration {
+	if c.ttl > 0 {
+		return c.ttl
+	}
+	return time.Duration(callbackTTL.Load())
+}
+
+// 不需要有效期时返回空
+func (c *CBData) timeSegment() string {
+	ttl := c.effectiveTTL()
+	if ttl <= 0 {
+		return ""
+	}
+
+	secs := int64((ttl + time.Second - 1) / time.Second)
+	return string(cbSegTime) + strconv.FormatInt(time.Now().Unix(), 10) + "," + strconv.FormatInt(secs, 10)
+}
+
+// 解析有效期段，返回有效期与是否已过期
+func parseTimeSegment(seg []string) (ttl time.Duration, expired bool, err error) {
+	if len(seg) != 2 {
+		return 0, false, fmt.Errorf("%w: malformed time segment", ErrInvalidCallbackData)
+	}
+
+	issued, err1 := strconv.ParseInt(seg[0][1:], 10, 64)
+	secs, err2 := strconv.ParseInt(seg[1], 10, 64)
+	if err1 != nil || err2 != nil || secs <= 0 {
+		return 0, false, fmt.Errorf("%w: malformed time segment", ErrInvalidCallbackData)
+	}
+
+	ttl = time.Duration(secs) * time.Second
+	return ttl, time.Now().After(time.Unix(issued, 0).Add(ttl)), nil
+}
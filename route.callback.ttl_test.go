@@ -0,0 +1,67 @@
+package mytgbot
+
+import (
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCallbackExpired(t *testing.T) {
+	fresh := NewCBData("home", "1").ExpireIn(time.Minute).Encode()
+	ret, err := Decode(fresh)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ret.effectiveTTL() != time.Minute {
+		t.Error("expect ttl kept, got:", ret.effectiveTTL())
+	}
+
+	issued := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	stale := "1;home,list;2;t" + issued + ",60"
+	ret, err = Decode(stale)
+	if !errors.Is(err, ErrCallbackExpired) || ret == nil || ret.Root() != "home" {
+		t.Error("expect expired data, got:", ret, err)
+		return
+	}
+
+	r := NewCallbackRouter()
+	var got string
+	r.Action("list", func(c *UpdateContext, data *CBData) error {
+		got = "list"
+		return nil
+	})
+	r.Expired(func(c *UpdateContext, data *CBData) error {
+		got = "expired:" + data.Root()
+		return nil
+	})
+
+	update := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{ID: "1", Data: stale}}
+	if err := r.Serve(&UpdateContext{Update: update}); err != nil || got != "expired:home" {
+		t.Error("unexpected result:", got, err)
+	}
+}
+
+func TestCallbackExpiredTamper(t *testing.T) {
+	SetCallbackSigner(&CallbackSigner{Secret: []byte("secret")})
+	defer SetCallbackSigner(nil)
+
+	str := NewCBData("home", "1").ExpireIn(time.Minute).Encode()
+	seg := strings.Split(str, ";")
+	if len(seg) != 5 || seg[3][0] != cbSegTime {
+		t.Error("unexpected encoded data:", str)
+		return
+	}
+
+	longer := strings.Replace(str, ","+strings.Split(seg[3], ",")[1]+";", ",99999;", 1)
+	removed := strings.Join(append(seg[:3:3], seg[4]), ";")
+	for _, forged := range []string{longer, removed} {
+		if _, err := Decode(forged); !errors.Is(err, ErrCallbackSignature) {
+			t.Errorf("%q: expect ErrCallbackSignature, got %v", forged, err)
+		}
+	}
+}
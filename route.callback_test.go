@@ -0,0 +1,163 @@
+package mytgbot
+
+import (
+	"errors"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCBDataLongPath(t *testing.T) {
+	data := NewCBData("home")
+	for i := 0; i < 10; i++ {
+		next := data.PushByData("settings", "page", "1")
+		data = &next
+	}
+
+	str := data.Encode()
+	if len(str) > MaxCallbackDataLen || !strings.HasPrefix(str, callbackStorePrefix) {
+		t.Error("expect stored token, got:", str)
+		return
+	}
+
+	ret, err := Decode(str)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ret.Path() != data.Path() || ret.PathLen() != 11 {
+		t.Error("unexpected path:", ret.Path())
+	}
+
+	prev := getCallbackStore()
+	SetCallbackStore(nil)
+	defer SetCallbackStore(prev)
+
+	if _, err := data.TryEncode(); !errors.Is(err, ErrCallbackDataTooLong) {
+		t.Error("expect ErrCallbackDataTooLong, got:", err)
+	}
+}
+
+func TestMemoryCallbackStore(t *testing.T) {
+	store := NewMemoryCallbackStore(0, 2)
+
+	first, _ := store.Put("a", "a1")
+	if again, _ := store.Put("a", "a2"); again != first {
+		t.Error("expect token reused for the same key")
+	}
+	if data, err := store.Get(first); err != nil || data != "a2" {
+		t.Error("expect latest data, got:", data, err)
+	}
+
+	if other, _ := NewMemoryCallbackStore(0, 2).Put("a", "a1"); other == first {
+		t.Error("expect random token, not derived from key")
+	}
+
+	second, _ := store.Put("b", "b")
+	_, _ = store.Put("c", "c")
+	if _, err := store.Get(first); !errors.Is(err, ErrCallbackDataNotFound) {
+		t.Error("expect oldest entry evicted, got:", err)
+	}
+	if data, err := store.Get(second); err != nil || data != "b" {
+		t.Error("unexpected data:", data, err)
+	}
+
+	prev := getCallbackStore()
+	SetCallbackStore(NewMemoryCallbackStore(0, 0))
+	defer SetCallbackStore(prev)
+
+	long := NewCBData("home").PushByData(strings.Repeat("settings", 8), "1")
+	str := long.Encode()
+	if long.Encode() != str || long.ForUser(42).Encode() == str {
+		t.Error("expect token reused only for the same payload and user")
+	}
+
+	//带有效期的按钮按签发时间区分，重新渲染不会覆盖旧按钮的数据
+	old := long.ExpireIn(time.Hour).Encode()
+	oldRaw, _ := loadCallbackData(old)
+	time.Sleep(time.Second)
+	if fresh := long.ExpireIn(time.Hour).Encode(); fresh == old {
+		t.Error("expect new token for a new issue time")
+	}
+	if raw, err := loadCallbackData(old); err != nil || raw != oldRaw {
+		t.Error("expect old button data kept, got:", raw, err)
+	}
+}
+
+func TestCBDataEscape(t *testing.T) {
+	data := NewCBData("search").PushByData("a;b\\c", "go,rust", "x;y", `\`, "")
+	str := data.Encode()
+
+	ret, err := Decode(str)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ret.Action() != "a;b\\c" || ret.PathLen() != 2 {
+		t.Error("unexpected path:", ret.PathList())
+	}
+
+	got := ret.GetData()
+	want := []string{"go,rust", "x;y", `\`, ""}
+	if strings.Join(got, "|") != strings.Join(want, "|") || len(got) != len(want) {
+		t.Errorf("expect %q, got %q", want, got)
+	}
+
+	if empty, _ := Decode(NewCBData("home").Encode()); empty == nil || !empty.IsEmptyData() {
+		t.Error("expect empty data")
+	}
+}
+
+func TestCBDataDecodeLegacy(t *testing.T) {
+	ret, err := Decode("p:home,list;d:2,abc")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if ret.Path() != "home,list" || ret.GetData()[0] != "2" || ret.GetData()[1] != "abc" {
+		t.Error("unexpected legacy data:", ret.PathList(), ret.GetData())
+	}
+
+	for _, str := range []string{"", "hello", "1;a;b;c\\", "1;a\\x;b", "1;a"} {
+		if _, err := Decode(str); !errors.Is(err, ErrInvalidCallbackData) {
+			t.Errorf("%q: expect ErrInvalidCallbackData, got %v", str, err)
+		}
+	}
+}
+
+func TestBuildNumberedList(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	res := BuildNumberedList(*NewCBData("list"), items, 5, 20, func(i int, item string) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(item, item)
+	}, PageKeyboard{Columns: 2, FirstLast: true, Indicator: true, Values: []string{"q"}})
+
+	if len(res.ButtonRows) != 4 || len(res.ButtonRows[0]) != 2 || len(res.ButtonRows[1]) != 1 {
+		t.Error("unexpected rows:", res.ButtonRows)
+		return
+	}
+
+	var texts []string
+	for _, btn := range res.ButtonRows[2] {
+		texts = append(texts, btn.Text)
+	}
+	if got := strings.Join(texts, " "); got != "« 1 … 4 [5] 6 … 20 »" {
+		t.Error("unexpected page row:", got)
+	}
+
+	next, err := Decode(*res.ButtonRows[2][len(texts)-1].CallbackData)
+	if err != nil || strings.Join(next.GetData(), ",") != "6,q" {
+		t.Error("unexpected next page:", next, err)
+	}
+
+	if ellipsis, _ := Decode(*res.ButtonRows[2][2].CallbackData); ellipsis.GetData()[0] != "2" {
+		t.Error("unexpected ellipsis page:", ellipsis.GetData())
+	}
+
+	if res.ButtonRows[3][0].Text != "5/20" {
+		t.Error("unexpected indicator:", res.ButtonRows[3][0].Text)
+	}
+}
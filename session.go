@@ -0,0 +1,280 @@
+package mytgbot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// Session 跨更新保存的数据，值以 JSON 保存，便于持久化
+	Session struct {
+		Version  int64                      `json:"version"` //每次保存加 1，用于乐观锁
+		Values   map[string]json.RawMessage `json:"values"`
+		ExpireAt time.Time                  `json:"expire_at"`
+		changed  bool
+		cleared  bool
+	}
+
+	// SessionStore 会话存储接口，没有或已过期时 Get 返回 nil, nil；
+	// Set 时 s.Version 与已保存的版本不一致返回 ErrSessionConflict，成功后 s.Version 加 1
+	SessionStore interface {
+		Get(key string) (*Session, error)
+		Set(key string, s *Session, ttl time.Duration) error
+		Delete(key string) error
+	}
+
+	// SessionKeyFunc 按更新生成会话的 key，返回空字符串时不加载会话
+	SessionKeyFunc func(c *UpdateContext) string
+
+	memorySessionStore struct {
+		mu        sync.Mutex
+		sessions  map[string]*Session
+		lastSweep time.Time
+	}
+
+	fileSessionStore struct {
+		memorySessionStore
+		path string
+	}
+)
+
+const sessionContextKey = "mytgbot.session"
+
+var ErrSessionConflict = errors.New("session was modified concurrently")
+
+func NewSession() *Session {
+	return &Session{Values: make(map[string]json.RawMessage)}
+}
+
+// Get 把 name 对应的值解析到 v，不存在或解析失败时返回 false
+func (s *Session) Get(name string, v any) bool {
+	data, ok := s.Values[name]
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func (s *Session) Set(name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if s.Values == nil {
+		s.Values = make(map[string]json.RawMessage)
+	}
+	s.Values[name] = data
+	s.changed = true
+	return nil
+}
+
+func (s *Session) Delete(name string) {
+	if _, ok := s.Values[name]; ok {
+		delete(s.Values, name)
+		s.changed = true
+	}
+}
+
+// Clear 清空会话，SessionMiddleware 会在 handler 之后从存储中删除
+func (s *Session) Clear() {
+	s.Values = make(map[string]json.RawMessage)
+	s.cleared = true
+}
+
+func (s *Session) Changed() bool {
+	return s.changed
+}
+
+// 私聊与群里分别保存：<chat>:<user>
+func SessionByChatUser(c *UpdateContext) string {
+	chatID, userID := c.ChatID(), c.UserID()
+	if chatID == 0 && userID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", chatID, userID)
+}
+
+// 同一用户在所有对话中共享：u:<user>
+func SessionByUser(c *UpdateContext) string {
+	if userID := c.UserID(); userID != 0 {
+		return fmt.Sprintf("u:%d", userID)
+	}
+	return ""
+}
+
+// 同一对话中所有用户共享：c:<chat>
+func SessionByChat(c *UpdateContext) string {
+	if chatID := c.ChatID(); chatID != 0 {
+		return fmt.Sprintf("c:%d", chatID)
+	}
+	return ""
+}
+
+// SessionMiddleware 在 handler 之前加载会话（SessionOf 获取），之后有修改时保存；
+// ttl 为会话保留时间，<=0 表示永久保留；keyFn 为 nil 时使用 SessionByChatUser
+func SessionMiddleware(store SessionStore, ttl time.Duration, keyFn SessionKeyFunc) Middleware {
+	if keyFn == nil {
+		keyFn = SessionByChatUser
+	}
+
+	return func(next Handler) Handler {
+		return func(c *UpdateContext) error {
+			key := keyFn(c)
+			if key == "" {
+				return next(c)
+			}
+
+			s, err := store.Get(key)
+			if err != nil {
+				return err
+			}
+			if s == nil {
+				s = NewSession()
+			}
+			c.Set(sessionContextKey, s)
+
+			err = next(c)
+
+			var saveErr error
+			switch {
+			case s.cleared && !s.changed:
+				saveErr = store.Delete(key)
+			case s.changed:
+				saveErr = store.Set(key, s, ttl)
+			}
+
+			if err != nil {
+				return err
+			}
+			return saveErr
+		}
+	}
+}
+
+// SessionOf 取 SessionMiddleware 加载的会话，未加载时返回 nil
+func SessionOf(c *UpdateContext) *Session {
+	v, ok := c.Get(sessionContextKey)
+	if !ok {
+		return nil
+	}
+	s, _ := v.(*Session)
+	return s
+}
+
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{
+		sessions:  make(map[string]*Session),
+		lastSweep: time.Now(),
+	}
+}
+
+func (m *memorySessionStore) Get(key string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.sessions[key]
+	if !ok || (!s.ExpireAt.IsZero() && time.Now().After(s.ExpireAt)) {
+		return nil, nil
+	}
+	return s.clone(), nil
+}
+
+func (m *memorySessionStore) Set(key string, s *Session, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.set(key, s, ttl)
+}
+
+func (m *memorySessionStore) set(key string, s *Session, ttl time.Duration) error {
+	now := time.Now()
+	m.sweep(now)
+
+	var version int64
+	if old, ok := m.sessions[key]; ok && (old.ExpireAt.IsZero() || !now.After(old.ExpireAt)) {
+		version = old.Version
+	}
+	if s.Version != version {
+		return ErrSessionConflict
+	}
+
+	saved := s.clone()
+	saved.Version++
+	saved.ExpireAt = time.Time{}
+	if ttl > 0 {
+		saved.ExpireAt = now.Add(ttl)
+	}
+	m.sessions[key] = saved
+
+	s.Version, s.ExpireAt, s.changed, s.cleared = saved.Version, saved.ExpireAt, false, false
+	return nil
+}
+
+func (m *memorySessionStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, key)
+	return nil
+}
+
+// 清理已过期的会话，最多每分钟一次
+func (m *memorySessionStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+
+	for key, s := range m.sessions {
+		if !s.ExpireAt.IsZero() && now.After(s.ExpireAt) {
+			delete(m.sessions, key)
+		}
+	}
+}
+
+func (s *Session) clone() *Session {
+	ret := &Session{Version: s.Version, ExpireAt: s.ExpireAt, Values: make(map[string]json.RawMessage, len(s.Values))}
+	for k, v := range s.Values {
+		ret.Values[k] = v
+	}
+	return ret
+}
+
+// NewFileSessionStore 以 JSON 文件保存会话，每次变更整体写回，适合会话数量不多的场景
+func NewFileSessionStore(path string) (SessionStore, error) {
+	f := &fileSessionStore{
+		memorySessionStore: memorySessionStore{sessions: make(map[string]*Session), lastSweep: time.Now()},
+		path:               path,
+	}
+
+	if err := readJSONFile(path, &f.sessions); err != nil {
+		return nil, err
+	}
+	if f.sessions == nil {
+		f.sessions = make(map[string]*Session)
+	}
+	return f, nil
+}
+
+func (f *fileSessionStore) Set(key string, s *Session, ttl time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.set(key, s, ttl); err != nil {
+		return err
+	}
+	return writeJSONFile(f.path, f.sessions)
+}
+
+func (f *fileSessionStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.sessions[key]; !ok {
+		return nil
+	}
+	delete(f.sessions, key)
+	return writeJSONFile(f.path, f.sessions)
+}
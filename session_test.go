@@ -0,0 +1,56 @@
+package mytgbot
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionMiddleware(t *testing.T) {
+	store, err := NewFileSessionStore(filepath.Join(t.TempDir(), "session.json"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	d := NewDispatcher(nil)
+	d.Use(SessionMiddleware(store, time.Hour, nil))
+
+	var count int
+	d.Handle(func(c *UpdateContext) error {
+		s := SessionOf(c)
+		s.Get("count", &count)
+		count++
+		return s.Set("count", count)
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := d.Dispatch(context.Background(), textUpdate(100, "hi")); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	reloaded, err := NewFileSessionStore(store.(*fileSessionStore).path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	s, err := reloaded.Get("100:100")
+	if err != nil || s == nil || s.Version != 3 || !s.Get("count", &count) || count != 3 {
+		t.Error("unexpected session:", s, err)
+		return
+	}
+
+	stale := s.clone()
+	_ = s.Set("count", 4)
+	if err := reloaded.Set("100:100", s, 0); err != nil {
+		t.Error(err)
+	}
+	if err := reloaded.Set("100:100", stale, 0); !errors.Is(err, ErrSessionConflict) {
+		t.Error("expect ErrSessionConflict, got:", err)
+	}
+}
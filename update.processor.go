@@ -0,0 +1,221 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	"github.com/any-call/gobase/util/mylog"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"runtime/debug"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProcessorWorkers = 8
+	defaultDedupWindow      = 5 * time.Minute
+	defaultMaxPending       = 100
+)
+
+type (
+	// UpdateProcessor 按 update_id 去重，同一对话的更新按 update_id 依次处理，不同对话由 worker 池并发处理；
+	// Process 的签名与 WebhookHandler 的 cbFun 一致，可直接作为 Poller 的 handler（Poller 按顺序提交）；
+	// 接 webhook 时使用 p.NewWebhookServer，多个 webhook worker 会打乱提交顺序
+	UpdateProcessor struct {
+		handler func(update tgbotapi.Update)
+		window  time.Duration
+
+		mu         sync.Mutex
+		maxPending int
+		cond       *sync.Cond //有对话待处理或已关闭
+		space      *sync.Cond //有对话的积压减少或已关闭
+		seen       map[int]struct{}
+		seenOrder  []seenUpdate
+		queues     map[int64][]tgbotapi.Update //有待处理更新的对话，在 ready 中或正在处理
+		ready      []int64
+		closed     bool
+		wg         sync.WaitGroup
+	}
+
+	seenUpdate struct {
+		id int
+		at time.Time
+	}
+)
+
+// NewUpdateProcessor workers 为并发处理的对话数，默认 8；window 为去重时间窗口，默认 5 分钟
+func NewUpdateProcessor(handler func(update tgbotapi.Update), workers int, window time.Duration) *UpdateProcessor {
+	if workers <= 0 {
+		workers = defaultProcessorWorkers
+	}
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+
+	p := &UpdateProcessor{
+		handler:    handler,
+		window:     window,
+		seen:       make(map[int]struct{}),
+		queues:     make(map[int64][]tgbotapi.Update),
+		maxPending: defaultMaxPending,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	p.space = sync.NewCond(&p.mu)
+
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+// SetMaxPending 每个对话最多积压的更新数（含正在处理的一条），默认 100，<=0 表示不限；
+// 达到上限时 Process 阻塞，见 Process
+func (p *UpdateProcessor) SetMaxPending(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxPending = n
+}
+
+// Process 提交一条更新，重复的更新与 Shutdown 之后提交的更新会被忽略。
+// 对话积压达到 SetMaxPending 的上限时阻塞到有空位，不丢弃更新：
+// 在 Poller 中 offset 不会前进，在 WebhookServer 中其队列随之积满并返回 503 让 Telegram 重试；
+// 因此不要在 handler 中为同一对话调用 Process。
+// update_id 在入队时即记为已处理，handler 执行中途的重复投递也会被过滤；
+// handler 没有返回值，panic 或失败的更新不会因重复投递而重试
+func (p *UpdateProcessor) Process(update tgbotapi.Update) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, dup := p.seen[update.UpdateID]; dup {
+		return
+	}
+
+	key := processKey(update)
+	for !p.closed && p.maxPending > 0 && len(p.queues[key]) >= p.maxPending {
+		p.space.Wait()
+	}
+
+	if p.closed || !p.markSeen(update.UpdateID) {
+		return
+	}
+
+	q, scheduled := p.queues[key]
+	p.queues[key] = insertByUpdateID(q, update)
+	if !scheduled {
+		p.ready = append(p.ready, key)
+		p.cond.Signal()
+	}
+}
+
+// Shutdown 停止接收新的更新，等待已提交的更新处理完毕或 ctx 结束
+func (p *UpdateProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.space.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// 每次取一个对话处理一条，处理完若还有待处理的更新则重新排队，避免单个对话占住 worker
+func (p *UpdateProcessor) work() {
+	defer p.wg.Done()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for {
+		for len(p.ready) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.ready) == 0 {
+			return
+		}
+
+		key := p.ready[0]
+		p.ready = p.ready[1:]
+		update := p.queues[key][0]
+
+		p.mu.Unlock()
+		p.handle(update)
+		p.mu.Lock()
+
+		if q := p.queues[key][1:]; len(q) == 0 {
+			delete(p.queues, key)
+		} else {
+			p.queues[key] = q
+			p.ready = append(p.ready, key)
+			p.cond.Signal()
+		}
+		p.space.Broadcast()
+	}
+}
+
+func (p *UpdateProcessor) handle(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			mylog.Error(fmt.Sprintf("panic in update(%d): %v\n%s", update.UpdateID, r, debug.Stack()))
+		}
+	}()
+
+	if p.handler != nil {
+		p.handler(update)
+	}
+}
+
+// 记录 update_id，已处理过时返回 false；同时清理窗口外的记录
+func (p *UpdateProcessor) markSeen(id int) bool {
+	now := time.Now()
+	n := 0
+	for n < len(p.seenOrder) && now.Sub(p.seenOrder[n].at) > p.window {
+		delete(p.seen, p.seenOrder[n].id)
+		n++
+	}
+	p.seenOrder = p.seenOrder[n:]
+
+	if _, ok := p.seen[id]; ok {
+		return false
+	}
+
+	p.seen[id] = struct{}{}
+	p.seenOrder = append(p.seenOrder, seenUpdate{id: id, at: now})
+	return true
+}
+
+// NewWebhookServer 以 p.Process 为 handler 创建 WebhookServer，并固定为一个 worker，
+// 保证更新按接收顺序交给 Process；并发处理由 UpdateProcessor 负责，opts 中的 worker 数量不生效
+func (p *UpdateProcessor) NewWebhookServer(opts ...WebhookOption) *WebhookServer {
+	return NewWebhookServer(p.Process, append(append([]WebhookOption(nil), opts...), withSingleWorker())...)
+}
+
+// 按 update_id 插入，先到的较大 id 排在后面；第一条可能正在处理，不会插到它之前
+func insertByUpdateID(q []tgbotapi.Update, update tgbotapi.Update) []tgbotapi.Update {
+	i := len(q)
+	for i > 1 && q[i-1].UpdateID > update.UpdateID {
+		i--
+	}
+	return slices.Insert(q, i, update)
+}
+
+// 按对话排队，没有对话的更新（如内联查询）按用户排队；私聊的 chat id 与用户 id 相同，二者顺序一致
+func processKey(update tgbotapi.Update) int64 {
+	if chatID := UpdateChatID(update); chatID != 0 {
+		return chatID
+	}
+
+	c := UpdateContext{Update: update}
+	return c.UserID()
+}
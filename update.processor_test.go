@@ -0,0 +1,154 @@
+package mytgbot
+
+import (
+	"context"
+	"fmt"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUpdateProcessor(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[int64][]int)
+	p := NewUpdateProcessor(func(update tgbotapi.Update) {
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := update.Message.Chat.ID
+		got[chatID] = append(got[chatID], update.UpdateID)
+	}, 4, time.Minute)
+
+	id := 0
+	for i := 0; i < 20; i++ {
+		for chatID := int64(1); chatID <= 3; chatID++ {
+			id++
+			update := textUpdate(chatID, "hi")
+			update.UpdateID = id
+			p.Process(update)
+			p.Process(update) //重复投递
+		}
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for chatID, ids := range got {
+		if len(ids) != 20 {
+			t.Errorf("chat %d: expect 20 updates, got %d", chatID, len(ids))
+		}
+		for i := 1; i < len(ids); i++ {
+			if ids[i] <= ids[i-1] {
+				t.Errorf("chat %d: out of order %v", chatID, ids)
+				break
+			}
+		}
+	}
+}
+
+func TestUpdateProcessorMaxPending(t *testing.T) {
+	block := make(chan struct{})
+	var mu sync.Mutex
+	var got []int
+	p := NewUpdateProcessor(func(update tgbotapi.Update) {
+		<-block
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, update.UpdateID)
+	}, 1, time.Minute)
+	p.SetMaxPending(2)
+
+	submitted := make(chan int, 3)
+	go func() {
+		for id := 1; id <= 3; id++ {
+			update := textUpdate(1, "hi")
+			update.UpdateID = id
+			p.Process(update)
+			submitted <- id
+		}
+	}()
+
+	//积压达到上限，第三条阻塞而不是丢弃
+	time.Sleep(50 * time.Millisecond)
+	if n := len(submitted); n != 2 {
+		t.Error("expect Process blocked at the limit, submitted:", n)
+	}
+
+	close(block)
+	if got := <-submitted + <-submitted + <-submitted; got != 6 {
+		t.Error("unexpected submitted:", got)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Error("unexpected handled updates:", got)
+	}
+}
+
+func TestUpdateProcessorWebhook(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[int64][]int)
+	p := NewUpdateProcessor(func(update tgbotapi.Update) {
+		mu.Lock()
+		defer mu.Unlock()
+		chatID := update.Message.Chat.ID
+		got[chatID] = append(got[chatID], update.UpdateID)
+	}, 4, time.Minute)
+
+	s := p.NewWebhookServer(WithWorkers(8, 256))
+	if s.workers != 1 {
+		t.Error("expect single webhook worker, got:", s.workers)
+	}
+
+	for id := 1; id <= 60; id++ {
+		body := fmt.Sprintf(`{"update_id":%d,"message":{"text":"hi","chat":{"id":%d}}}`, id, id%3+1)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Error("unexpected status:", w.Code)
+		}
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for chatID, ids := range got {
+		if len(ids) != 20 || !slices.IsSorted(ids) {
+			t.Errorf("chat %d: unexpected updates %v", chatID, ids)
+		}
+	}
+}
+
+func TestInsertByUpdateID(t *testing.T) {
+	var q []tgbotapi.Update
+	for _, id := range []int{5, 3, 7, 6} {
+		q = insertByUpdateID(q, tgbotapi.Update{UpdateID: id})
+	}
+
+	//第一条可能正在处理，保持在最前
+	var ids []int
+	for _, u := range q {
+		ids = append(ids, u.UpdateID)
+	}
+	if !slices.Equal(ids, []int{5, 3, 6, 7}) {
+		t.Error("unexpected order:", ids)
+	}
+}
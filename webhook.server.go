@@ -0,0 +1,192 @@
+package mytgbot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/any-call/gobase/util/mylog"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"runtime/debug"
+	"sync"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+	defaultWebhookMaxBody   = 1 << 20
+	defaultWebhookWorkers   = 4
+	defaultWebhookQueueSize = 256
+)
+
+type (
+	// WebhookServer 接收 Telegram 推送的更新：校验 secret token、限制请求大小、
+	// 入队后立即返回 200，由固定数量的 worker 异步处理
+	WebhookServer struct {
+		handler   func(update tgbotapi.Update)
+		secret    string
+		maxBody   int64
+		workers   int
+		queueSize int
+
+		mu     sync.RWMutex
+		queue  chan tgbotapi.Update
+		closed bool
+		wg     sync.WaitGroup
+	}
+
+	WebhookOption func(s *WebhookServer)
+)
+
+var ErrWebhookServerClosed = errors.New("webhook server is closed")
+
+// WithSecretToken 与 setWebhook 的 secret_token 一致，为空时不校验
+func WithSecretToken(secret string) WebhookOption {
+	return func(s *WebhookServer) {
+		s.secret = secret
+	}
+}
+
+// WithMaxBodySize 请求体上限，默认 1MB
+func WithMaxBodySize(n int64) WebhookOption {
+	return func(s *WebhookServer) {
+		s.maxBody = n
+	}
+}
+
+// WithWorkers worker 数量与队列长度，默认 4 与 256；队列满时返回 503 让 Telegram 稍后重试
+func WithWorkers(workers, queueSize int) WebhookOption {
+	return func(s *WebhookServer) {
+		s.workers = workers
+		s.queueSize = queueSize
+	}
+}
+
+// 只用一个 worker，队列长度不变，见 UpdateProcessor.NewWebhookServer
+func withSingleWorker() WebhookOption {
+	return func(s *WebhookServer) {
+		s.workers = 1
+	}
+}
+
+// NewWebhookServer handler 签名与 WebhookHandler 的 cbFun 一致，如 dispatcher.ServeUpdate
+func NewWebhookServer(handler func(update tgbotapi.Update), opts ...WebhookOption) *WebhookServer {
+	s := &WebhookServer{
+		handler:   handler,
+		maxBody:   defaultWebhookMaxBody,
+		workers:   defaultWebhookWorkers,
+		queueSize: defaultWebhookQueueSize,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.workers <= 0 {
+		s.workers = defaultWebhookWorkers
+	}
+	if s.queueSize < 0 {
+		s.queueSize = 0
+	}
+
+	s.queue = make(chan tgbotapi.Update, s.queueSize)
+	for i := 0; i < s.workers; i++ {
+		s.wg.Add(1)
+		go s.work()
+	}
+	return s
+}
+
+func (s *WebhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.secret != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get(secretTokenHeader)), []byte(s.secret)) != 1 {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if s.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Cannot decode update", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.enqueue(update); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *WebhookServer) enqueue(update tgbotapi.Update) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrWebhookServerClosed
+	}
+
+	select {
+	case s.queue <- update:
+		return nil
+	default:
+		return fmt.Errorf("webhook queue is full")
+	}
+}
+
+// Shutdown 停止接收新的更新，等待队列中的更新处理完毕或 ctx 结束
+func (s *WebhookServer) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *WebhookServer) work() {
+	defer s.wg.Done()
+	for update := range s.queue {
+		s.handle(update)
+	}
+}
+
+// handler panic 不影响 worker 继续处理
+func (s *WebhookServer) handle(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			mylog.Error(fmt.Sprintf("panic in webhook update(%d): %v\n%s", update.UpdateID, r, debug.Stack()))
+		}
+	}()
+
+	if s.handler != nil {
+		s.handler(update)
+	}
+}
@@ -0,0 +1,57 @@
+package mytgbot
+
+import (
+	"context"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookServer(t *testing.T) {
+	var handled atomic.Int32
+	s := NewWebhookServer(func(update tgbotapi.Update) {
+		time.Sleep(time.Millisecond * 100)
+		handled.Add(1)
+	}, WithSecretToken("secret"), WithMaxBodySize(256), WithWorkers(2, 8))
+
+	post := func(secret, body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(body))
+		r.Header.Set(secretTokenHeader, secret)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post("wrong", `{"update_id":1}`); code != http.StatusUnauthorized {
+		t.Error("expect 401, got:", code)
+	}
+
+	if code := post("secret", `{"update_id":1,"message":{"text":"`+strings.Repeat("a", 512)+`"}}`); code != http.StatusRequestEntityTooLarge {
+		t.Error("expect 413, got:", code)
+	}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if code := post("secret", `{"update_id":1}`); code != http.StatusOK {
+			t.Error("expect 200, got:", code)
+		}
+	}
+	if cost := time.Since(start); cost > time.Millisecond*100 {
+		t.Error("ack waited for handler:", cost)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+	if n := handled.Load(); n != 4 {
+		t.Error("expect 4 updates drained, got:", n)
+	}
+
+	if code := post("secret", `{"update_id":2}`); code != http.StatusServiceUnavailable {
+		t.Error("expect 503 after shutdown, got:", code)
+	}
+}